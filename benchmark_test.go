@@ -101,11 +101,11 @@ type hybridInitSplicer struct {
 }
 
 func (is *hybridInitSplicer) Init(str string) {
-	is.Hybrid = trope.Hybrid{15000, 10000, Slicer(""), 0, trope.New(Slicer(str), len(str))}
+	is.Hybrid = trope.Hybrid{HighMark: 15000, LowMark: 10000, Raw: Slicer(""), Count: 0, Node: trope.New(Slicer(str), len(str))}
 }
 
 func (is *hybridInitSplicer) Splice(offset, count int, r string) {
-	replace := trope.Hybrid{15000, 10000, Slicer(r), len(r), trope.Node{}}
+	replace := trope.Hybrid{HighMark: 15000, LowMark: 10000, Raw: Slicer(r), Count: len(r), Node: trope.Node{}}
 	is.Hybrid = is.Hybrid.Splice(offset, count, replace)
 }
 
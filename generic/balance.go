@@ -0,0 +1,177 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+// Rebalance returns a B-tree like version of the node: all the leaf
+// nodes are at the same depth and every internal node other than the
+// root has between minFanout and maxFanout children.  The root has at
+// most maxFanout children.  The height is thus logarithmic in the
+// number of leaves.
+//
+// The minFanout must be at least 2 and maxFanout must be at least
+// 2*minFanout - 1.
+func (n Node[T]) Rebalance(minFanout, maxFanout int) Node[T] {
+	if !validFanout(minFanout, maxFanout) {
+		panic("Unexpected fan-out")
+	}
+	if opts := n.Options(); opts.Balanced {
+		opts.MinFanout, opts.MaxFanout = minFanout, maxFanout
+		n.opts = &opts
+	}
+	return n.rebalance(maxFanout)
+}
+
+// Balanced is like Rebalance but it also sets the Balanced option on
+// the result so that Splice, Slice and Flatten on it (and on nodes
+// derived from it) maintain the same invariants incrementally.
+// Edits then take time logarithmic in the number of leaves.
+func (n Node[T]) Balanced(minFanout, maxFanout int) Node[T] {
+	opts := n.Options()
+	opts.Balanced, opts.MinFanout, opts.MaxFanout = true, minFanout, maxFanout
+	return n.WithOptions(opts)
+}
+
+func (n Node[T]) rebalance(maxFanout int) Node[T] {
+	level := []Node[T](nil)
+	n.forEach(func(leaf Node[T]) {
+		level = append(level, leaf)
+	})
+	if len(level) == 0 {
+		return n.empty()
+	}
+
+	for len(level) > 1 {
+		level = n.group(level, maxFanout)
+	}
+	return n.root(level[0])
+}
+
+// group splits nodes evenly into as few parents as possible
+func (n Node[T]) group(nodes []Node[T], max int) []Node[T] {
+	groups := (len(nodes) + max - 1) / max
+	result := make([]Node[T], 0, groups)
+	for kk := 0; kk < groups; kk++ {
+		start, end := kk*len(nodes)/groups, (kk+1)*len(nodes)/groups
+		result = append(result, n.parent(nodes[start:end:end]))
+	}
+	return result
+}
+
+// parent creates a new internal node with the provided children
+func (n Node[T]) parent(children []Node[T]) Node[T] {
+	count := 0
+	for _, child := range children {
+		count += child.Count
+	}
+	return Node[T]{ids: n.ids, opts: n.opts, Children: children, Count: count}.fresh()
+}
+
+// siblings is like parent but it avoids creating empty or single
+// child nodes
+func (n Node[T]) siblings(children []Node[T]) Node[T] {
+	switch len(children) {
+	case 0:
+		return n.empty()
+	case 1:
+		return children[0]
+	}
+	return n.parent(children[:len(children):len(children)])
+}
+
+func (n Node[T]) empty() Node[T] {
+	return Node[T]{ids: n.ids, opts: n.opts}.fresh()
+}
+
+// root makes r carry the same settings as n
+func (n Node[T]) root(r Node[T]) Node[T] {
+	r.ids, r.opts = n.ids, n.opts
+	return r
+}
+
+func (n Node[T]) height() int {
+	h := 0
+	for ; n.Children != nil; n = n.Children[0] {
+		h++
+	}
+	return h
+}
+
+func (n Node[T]) sliceBalanced(offset, count int) Node[T] {
+	_, right := n.split(n, offset)
+	mid, _ := n.split(right, count)
+	return n.root(mid).fresh()
+}
+
+func (n Node[T]) spliceBalanced(offset, count int, replacement Node[T]) Node[T] {
+	if replacement.Children != nil && replacement.Options() != n.Options() {
+		replacement = replacement.rebalance(n.opts.MaxFanout)
+	}
+
+	left, right := n.split(n, offset)
+	_, right = n.split(right, count)
+	return n.root(n.concat(n.concat(left, replacement), right)).fresh()
+}
+
+// split divides the balanced tree r into two balanced trees at the
+// provided offset
+func (n Node[T]) split(r Node[T], offset int) (Node[T], Node[T]) {
+	switch {
+	case offset == 0:
+		return n.empty(), r
+	case offset == r.Count:
+		return r, n.empty()
+	case r.Children == nil:
+		return r.sliceLeaf(0, offset), r.sliceLeaf(offset, r.Count-offset)
+	}
+
+	kk, offset := r.child(offset)
+	left, right := n.split(r.Children[kk], offset)
+	left = n.concat(n.siblings(r.Children[:kk]), left)
+	right = n.concat(right, n.siblings(r.Children[kk+1:]))
+	return left, right
+}
+
+// concat joins two balanced trees into a balanced tree
+func (n Node[T]) concat(a, b Node[T]) Node[T] {
+	switch {
+	case a.Count == 0:
+		return b
+	case b.Count == 0:
+		return a
+	}
+
+	nodes := n.merge(a, a.height(), b, b.height())
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return n.parent(nodes)
+}
+
+// merge joins a and b into one or two nodes of the larger of the two
+// heights.  The nodes along the seam are merged or split as needed
+// to keep the fan-out within bounds.
+func (n Node[T]) merge(a Node[T], ha int, b Node[T], hb int) []Node[T] {
+	var children []Node[T]
+	switch {
+	case ha == 0 && hb == 0:
+		if leaf, ok := n.mergeLeaves(a, b, n.opts.MinLeafSize); ok {
+			return []Node[T]{leaf}
+		}
+		return []Node[T]{a, b}
+	case ha > hb:
+		last := len(a.Children) - 1
+		children = append([]Node[T](nil), a.Children[:last]...)
+		children = append(children, n.merge(a.Children[last], ha-1, b, hb)...)
+	case ha < hb:
+		children = n.merge(a, ha, b.Children[0], hb-1)
+		children = append(children, b.Children[1:]...)
+	case len(a.Children) >= n.opts.MinFanout && len(b.Children) >= n.opts.MinFanout:
+		return []Node[T]{a, b}
+	default:
+		children = append([]Node[T](nil), a.Children...)
+		children = append(children, b.Children...)
+	}
+	return n.group(children, n.opts.MaxFanout)
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func TestRebalance(t *testing.T) {
+	n := generic.New(text("a"), 1)
+	str := "a"
+	for kk := 0; kk < 500; kk++ {
+		n = n.Splice(0, 0, generic.New(text("b"), 1))
+		str = "b" + str
+	}
+
+	r := n.Rebalance(2, 4)
+	if x := toString(r); x != str {
+		t.Fatal("Rebalance changed contents", x)
+	}
+	if h := checkBalanced(t, r, 2, 4, true); h > 9 {
+		t.Fatal("Rebalance height", h)
+	}
+
+	if x := toString(r.Splice(0, 0, generic.New(text("c"), 1))); x != "c"+str {
+		t.Fatal("Splice on rebalanced", x)
+	}
+
+	if x := generic.New(text(""), 0).Rebalance(2, 3); x.Count != 0 {
+		t.Fatal("Rebalance empty", x)
+	}
+
+	mustPanic(t, func() { n.Rebalance(1, 4) })
+	mustPanic(t, func() { n.Rebalance(3, 4) })
+}
+
+func TestBalancedSplices(t *testing.T) {
+	rand.Seed(42)
+	n := generic.New(text("hello"), 5).Balanced(2, 3)
+	str := "hello"
+	for kk := 0; kk < 2000; kk++ {
+		offset := rand.Intn(len(str) + 1)
+		count := rand.Intn(len(str) - offset + 1)
+		if count > 5 {
+			count = rand.Intn(5)
+		}
+		r := text(randomText(rand.Intn(4)))
+		replacement := generic.New(r, len(r))
+		if kk%7 == 0 {
+			replacement = replacement.Splice(0, 0, generic.New(text("xy"), 2))
+			r = "xy" + r
+		}
+
+		n = n.Splice(offset, count, replacement)
+		str = str[:offset] + string(r) + str[offset+count:]
+		if x := toString(n); x != str {
+			t.Fatal("Diverged", kk, x, str)
+		}
+		checkBalanced(t, n, 2, 3, true)
+
+		offset = rand.Intn(len(str) + 1)
+		count = rand.Intn(len(str) - offset + 1)
+		slice := n.Slice(offset, count)
+		if x := toString(slice); x != str[offset:offset+count] {
+			t.Fatal("Slice diverged", kk, x)
+		}
+		checkBalanced(t, slice, 2, 3, true)
+	}
+
+	if x := toString(n.Flatten(100)); x != str {
+		t.Fatal("Flatten diverged", x)
+	}
+	checkBalanced(t, n.Flatten(100), 2, 3, true)
+}
+
+// checkBalanced verifies the B-tree invariants and returns the height
+func checkBalanced(t *testing.T, n generic.Node[text], min, max int, root bool) int {
+	t.Helper()
+	if n.Children == nil {
+		return 0
+	}
+
+	if len(n.Children) > max || len(n.Children) < 2 || !root && len(n.Children) < min {
+		t.Fatal("Unexpected fan-out", len(n.Children))
+	}
+
+	height, count := -1, 0
+	for _, child := range n.Children {
+		if child.Count == 0 {
+			t.Fatal("Unexpected empty child")
+		}
+		h := checkBalanced(t, child, min, max, false)
+		if height != -1 && h != height {
+			t.Fatal("Unbalanced heights", h, height)
+		}
+		height = h
+		count += child.Count
+	}
+	if count != n.Count {
+		t.Fatal("Unexpected count", count, n.Count)
+	}
+	return height + 1
+}
+
+func randomText(size int) string {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = "abcdefghijklmnopqrstuvwxyz"[rand.Intn(26)]
+	}
+	return string(b)
+}
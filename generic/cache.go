@@ -0,0 +1,86 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "sync/atomic"
+
+// nodeCache holds the values computed from the contents of a node,
+// such as its hash and summaries.  Nodes are immutable, so these are
+// computed at most once per node.  Every new node gets its own cache
+// and the cache is only used by nodes with the ID it was created for.
+//
+// A copy of a node shares its cache, so a node changed in place
+// (rather than via its methods) would see the values of the original.
+// Each value is stored with the Count and the leaf-ness of the node
+// it was computed for and is not used for a node which differs in
+// those, but other changes (such as a Leaf with the same Count) go
+// unnoticed: see Node.
+type nodeCache struct {
+	id     int
+	values atomic.Pointer[[]cachedValue]
+}
+
+// cachedValue is a value stored in a nodeCache along with its key and
+// the shape of the node it was computed for
+type cachedValue struct {
+	key, value any
+	count      int
+	leaf       bool
+}
+
+// load returns the value stored for n with the key
+func (n Node[T]) load(key any) (any, bool) {
+	c := n.cached()
+	if c == nil {
+		return nil, false
+	}
+	if values := c.values.Load(); values != nil {
+		for _, v := range *values {
+			if v.key == key && v.count == n.Count && v.leaf == (n.Children == nil) {
+				return v.value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// store stores the value for n with the key
+func (n Node[T]) store(key, value any) {
+	c := n.cached()
+	if c == nil {
+		return
+	}
+	for {
+		old := c.values.Load()
+		values := []cachedValue{{key, value, n.Count, n.Children == nil}}
+		if old != nil {
+			values = append(values, *old...)
+		}
+		if c.values.CompareAndSwap(old, &values) {
+			return
+		}
+	}
+}
+
+// fresh returns n with a new ID (from the ID space of n) and an
+// empty cache
+func (n Node[T]) fresh() Node[T] {
+	n.ID = n.ids.next()
+	return n.withCache()
+}
+
+// withCache returns n with an empty cache for its current ID
+func (n Node[T]) withCache() Node[T] {
+	n.cache = &nodeCache{id: n.ID}
+	return n
+}
+
+// cached returns the cache of n or nil if n has none
+func (n Node[T]) cached() *nodeCache {
+	if n.cache == nil || n.cache.id != n.ID {
+		return nil
+	}
+	return n.cache
+}
@@ -0,0 +1,249 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "math"
+
+// Op is a single operation of a Delta.  Exactly one of the fields is
+// set: Retain skips over elements, Delete removes elements and Insert
+// adds the elements of the node.
+type Op[T any] struct {
+	Retain, Delete int
+	Insert         Node[T]
+}
+
+// Len is the number of elements the op retains, deletes or inserts
+func (op Op[T]) Len() int {
+	return op.Retain + op.Delete + op.Insert.Count
+}
+
+// Delta is a change to a node expressed as a sequence of ops which
+// walk the node from the start.  Any elements past the last op are
+// retained.
+//
+// Use the Retain, Delete and Insert methods to build a delta: they
+// combine adjacent ops of the same kind and skip empty ones.
+type Delta[T any] struct {
+	Ops []Op[T]
+}
+
+// Retain adds an op to retain count elements
+func (d *Delta[T]) Retain(count int) *Delta[T] {
+	return d.push(Op[T]{Retain: count})
+}
+
+// Delete adds an op to delete count elements
+func (d *Delta[T]) Delete(count int) *Delta[T] {
+	return d.push(Op[T]{Delete: count})
+}
+
+// Insert adds an op to insert the elements of n
+func (d *Delta[T]) Insert(n Node[T]) *Delta[T] {
+	return d.push(Op[T]{Insert: n})
+}
+
+func (d *Delta[T]) push(op Op[T]) *Delta[T] {
+	if op.Len() == 0 {
+		return d
+	}
+
+	last := len(d.Ops) - 1
+	switch {
+	case last < 0:
+	case op.Retain > 0 && d.Ops[last].Retain > 0:
+		d.Ops[last].Retain += op.Retain
+		return d
+	case op.Delete > 0 && d.Ops[last].Delete > 0:
+		d.Ops[last].Delete += op.Delete
+		return d
+	case op.Insert.Count > 0 && d.Ops[last].Delete > 0:
+		// keep inserts ahead of deletes so that equivalent deltas
+		// have the same ops
+		del := d.Ops[last]
+		d.Ops = d.Ops[:last]
+		return d.push(op).push(del)
+	}
+	d.Ops = append(d.Ops, op)
+	return d
+}
+
+// trim drops the trailing retain, which is implied
+func (d *Delta[T]) trim() Delta[T] {
+	if last := len(d.Ops) - 1; last >= 0 && d.Ops[last].Retain > 0 {
+		d.Ops = d.Ops[:last]
+	}
+	return *d
+}
+
+// BaseLen is the minimum size of the nodes the delta can be applied to
+func (d Delta[T]) BaseLen() int {
+	size := 0
+	for _, op := range d.Ops {
+		size += op.Retain + op.Delete
+	}
+	return size
+}
+
+// DeltaFromEdits converts the edits returned by Diff into a delta
+func DeltaFromEdits[T any](edits []Edit[T]) Delta[T] {
+	var d Delta[T]
+	offset := 0
+	for _, e := range edits {
+		d.Retain(e.Offset - offset).Insert(e.Insert).Delete(e.Count)
+		offset = e.Offset + e.Insert.Count
+	}
+	return d.trim()
+}
+
+// Apply applies the delta to n.  It panics if n has fewer than
+// BaseLen elements.
+func (d Delta[T]) Apply(n Node[T]) Node[T] {
+	if d.BaseLen() > n.Count {
+		panic("Unexpected offset, count")
+	}
+
+	offset := 0
+	for _, op := range d.Ops {
+		switch {
+		case op.Retain > 0:
+			offset += op.Retain
+		case op.Delete > 0:
+			n = n.Delete(offset, op.Delete)
+		default:
+			n = n.Splice(offset, 0, op.Insert)
+			offset += op.Insert.Count
+		}
+	}
+	return n
+}
+
+// Compose returns a single delta which has the same effect as
+// applying a and then b.
+func Compose[T any](a, b Delta[T]) Delta[T] {
+	var result Delta[T]
+	ia, ib := &opIter[T]{ops: a.Ops}, &opIter[T]{ops: b.Ops}
+	for ia.more() || ib.more() {
+		aop, acount := ia.peek()
+		bop, bcount := ib.peek()
+		switch {
+		case bop.Insert.Count > 0:
+			result.push(ib.next(bcount))
+		case aop.Delete > 0:
+			result.push(ia.next(acount))
+		default:
+			count := min(acount, bcount)
+			aop, bop = ia.next(count), ib.next(count)
+			switch {
+			case bop.Retain > 0:
+				result.push(aop)
+			case aop.Retain > 0:
+				result.push(bop)
+			}
+		}
+	}
+	return result.trim()
+}
+
+// Invert returns the delta which undoes d when applied to the result
+// of d.Apply(base).
+func (d Delta[T]) Invert(base Node[T]) Delta[T] {
+	var result Delta[T]
+	offset := 0
+	for _, op := range d.Ops {
+		switch {
+		case op.Retain > 0:
+			result.Retain(op.Retain)
+		case op.Delete > 0:
+			result.Insert(base.Slice(offset, op.Delete))
+		default:
+			result.Delete(op.Insert.Count)
+		}
+		offset += op.Retain + op.Delete
+	}
+	return result.trim()
+}
+
+// Transform adjusts b, a concurrent change to the same node as a, so
+// that it can be applied after a.  The result of applying a and then
+// Transform(a, b, priority) is the same as the result of applying b
+// and then Transform(b, a, !priority).
+//
+// When both insert at the same offset, the inserts of a go first if
+// priority is set.
+func Transform[T any](a, b Delta[T], priority bool) Delta[T] {
+	var result Delta[T]
+	ia, ib := &opIter[T]{ops: a.Ops}, &opIter[T]{ops: b.Ops}
+	for ia.more() || ib.more() {
+		aop, acount := ia.peek()
+		bop, bcount := ib.peek()
+		switch {
+		case aop.Insert.Count > 0 && (priority || bop.Insert.Count == 0):
+			result.Retain(acount)
+			ia.skip(acount)
+		case bop.Insert.Count > 0:
+			result.push(ib.next(bcount))
+		default:
+			count := min(acount, bcount)
+			ia.skip(count)
+			ib.skip(count)
+			switch {
+			case aop.Delete > 0:
+			case bop.Delete > 0:
+				result.Delete(count)
+			default:
+				result.Retain(count)
+			}
+		}
+	}
+	return result.trim()
+}
+
+// opIter walks the ops of a delta, splitting them as needed.  Once
+// the ops are exhausted, it returns an endless retain.
+type opIter[T any] struct {
+	ops    []Op[T]
+	offset int
+}
+
+func (it *opIter[T]) more() bool {
+	return len(it.ops) > 0
+}
+
+// peek returns the current op and the number of elements left in it
+func (it *opIter[T]) peek() (Op[T], int) {
+	if len(it.ops) == 0 {
+		return Op[T]{Retain: math.MaxInt}, math.MaxInt
+	}
+	return it.ops[0], it.ops[0].Len() - it.offset
+}
+
+// next returns the next count elements of the current op
+func (it *opIter[T]) next(count int) Op[T] {
+	if len(it.ops) == 0 {
+		return Op[T]{Retain: count}
+	}
+	result := it.ops[0].slice(it.offset, count)
+	it.skip(count)
+	return result
+}
+
+func (it *opIter[T]) skip(count int) {
+	if len(it.ops) == 0 {
+		return
+	}
+	if it.offset += count; it.offset == it.ops[0].Len() {
+		it.ops, it.offset = it.ops[1:], 0
+	}
+}
+
+func (op Op[T]) slice(offset, count int) Op[T] {
+	switch {
+	case op.Retain > 0:
+		return Op[T]{Retain: count}
+	case op.Delete > 0:
+		return Op[T]{Delete: count}
+	}
+	return Op[T]{Insert: op.Insert.Slice(offset, count)}
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func TestDeltas(t *testing.T) {
+	rand.Seed(42)
+	for kk := 0; kk < 500; kk++ {
+		s := randomText(rand.Intn(30))
+		doc := generic.New(text(s), len(s))
+		a, b := randomDelta(doc), randomDelta(doc)
+		afterA, afterB := a.Apply(doc), b.Apply(doc)
+
+		next := randomDelta(afterA)
+		if x, y := toString(generic.Compose(a, next).Apply(doc)), toString(next.Apply(afterA)); x != y {
+			t.Fatal("Compose", x, y)
+		}
+
+		if x := toString(a.Invert(doc).Apply(afterA)); x != toString(doc) {
+			t.Fatal("Invert", x, toString(doc))
+		}
+
+		x := toString(generic.Transform(a, b, true).Apply(afterA))
+		y := toString(generic.Transform(b, a, false).Apply(afterB))
+		if x != y {
+			t.Fatal("Transform", x, y)
+		}
+
+		d := generic.DeltaFromEdits(generic.Diff(doc, afterA))
+		if x := toString(d.Apply(doc)); x != toString(afterA) {
+			t.Fatal("DeltaFromEdits", x, toString(afterA))
+		}
+	}
+}
+
+func TestDeltaOps(t *testing.T) {
+	f := generic.NewForest[text]()
+	doc := f.New("hello world", 11)
+
+	var d generic.Delta[text]
+	d.Retain(5).Retain(0).Delete(1).Insert(f.New(", ", 2)).Retain(5)
+	if len(d.Ops) != 4 || d.Ops[1].Insert.Count != 2 || d.Ops[2].Delete != 1 || d.BaseLen() != 11 {
+		t.Fatal("Unexpected ops", d.Ops)
+	}
+	if x := toString(d.Apply(doc)); x != "hello, world" {
+		t.Fatal("Apply", x)
+	}
+
+	var ins generic.Delta[text]
+	ins.Insert(f.New(">", 1))
+	if x := toString(generic.Transform(ins, ins, true).Apply(ins.Apply(doc))); x != ">>hello world" {
+		t.Fatal("Transform", x)
+	}
+
+	mustPanic(t, func() { d.Apply(f.New("short", 5)) })
+}
+
+// randomDelta creates a random delta that applies to doc
+func randomDelta(doc generic.Node[text]) generic.Delta[text] {
+	var d generic.Delta[text]
+	for offset := 0; offset < doc.Count; {
+		count := 1 + rand.Intn(doc.Count-offset)
+		switch rand.Intn(3) {
+		case 0:
+			d.Retain(count)
+			offset += count
+		case 1:
+			d.Delete(count)
+			offset += count
+		default:
+			s := randomText(1 + rand.Intn(3))
+			d.Insert(doc.Forest().New(text(s), len(s)))
+		}
+	}
+	return d
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Edit is a single splice: Count elements at Offset are replaced by
+// the elements of Insert.
+type Edit[T any] struct {
+	Offset, Count int
+	Insert        Node[T]
+}
+
+// Diff returns the splices that turn old into new.  The edits are
+// ordered by offset and are meant to be applied in order, so the
+// offset of each edit accounts for the earlier edits:
+//
+//	for _, e := range Diff(old, new) {
+//		old = old.Splice(e.Offset, e.Count, e.Insert)
+//	}
+//
+// Diff relies on the IDs of the nodes: subtrees with the same ID in
+// both trees are considered equal and are not visited at all.  The
+// time taken is thus proportional to the size of the changes rather
+// than the size of the trees.  Nodes from different ID spaces share
+// nothing, so the result then simply replaces everything.
+//
+// The changed leaves are compared by hash (see Node.Hash) when the
+// leaves implement Hasher and Slicer, so that the edits of a leaf
+// only cover the elements from the first to the last one that
+// changed rather than the whole leaf.
+func Diff[T any](old, new Node[T]) []Edit[T] {
+	switch {
+	case old.ID == new.ID && old.ids == new.ids && old.Count == new.Count:
+		return nil
+	case old.Count == 0 && new.Count == 0:
+		return nil
+	case old.ids != new.ids:
+		return []Edit[T]{{0, old.Count, new}}
+	}
+
+	sides := [2]*diffSide[T]{newDiffSide(old), newDiffSide(new)}
+	q := &diffQueue[T]{}
+	heap.Push(q, diffItem[T]{old, 0})
+	heap.Push(q, diffItem[T]{new, 1})
+	for q.Len() > 0 {
+		item := heap.Pop(q).(diffItem[T])
+		n, side, other := item.Node, sides[item.side], sides[1-item.side]
+		if n.Children == nil || other.present[n.ID] > 0 || side.expanded[n.ID] {
+			continue
+		}
+		side.present[n.ID]--
+		side.expanded[n.ID] = true
+		for _, child := range n.Children {
+			side.present[child.ID]++
+			heap.Push(q, diffItem[T]{child, item.side})
+		}
+	}
+
+	return sides[0].edits(sides[1], new)
+}
+
+// diffSide tracks the frontier of one of the trees: the nodes that
+// have been reached but not expanded.
+type diffSide[T any] struct {
+	root     Node[T]
+	present  map[int]int
+	expanded map[int]bool
+	items    []Node[T]
+}
+
+func newDiffSide[T any](root Node[T]) *diffSide[T] {
+	return &diffSide[T]{root, map[int]int{root.ID: 1}, map[int]bool{}, nil}
+}
+
+// frontier lists the unexpanded nodes in order
+func (s *diffSide[T]) frontier(n Node[T]) {
+	switch {
+	case n.Count == 0:
+	case s.expanded[n.ID]:
+		for _, child := range n.Children {
+			s.frontier(child)
+		}
+	default:
+		s.items = append(s.items, n)
+	}
+}
+
+// edits matches up the frontiers of both sides and returns the edits
+// for the unmatched stretches between them
+func (s *diffSide[T]) edits(o *diffSide[T], new Node[T]) []Edit[T] {
+	s.frontier(s.root)
+	o.frontier(o.root)
+
+	index := map[int]int{}
+	for kk, n := range s.items {
+		if _, ok := index[n.ID]; ok {
+			index[n.ID] = -1
+		} else {
+			index[n.ID] = kk
+		}
+	}
+	seen := map[int]int{}
+	for _, n := range o.items {
+		seen[n.ID]++
+	}
+
+	var pairs [][2]int
+	for kk, n := range o.items {
+		if jj, ok := index[n.ID]; ok && jj >= 0 && seen[n.ID] == 1 && s.items[jj].Count == n.Count {
+			pairs = append(pairs, [2]int{jj, kk})
+		}
+	}
+	pairs = increasing(pairs)
+
+	var result []Edit[T]
+	offset, from, last := 0, 0, [2]int{-1, -1}
+	for _, pair := range append(pairs, [2]int{len(s.items), len(o.items)}) {
+		count := 0
+		for _, n := range s.items[last[0]+1 : pair[0]] {
+			count += n.Count
+		}
+		inserted := o.items[last[1]+1 : pair[1]]
+		if count > 0 || len(inserted) > 0 {
+			insert := new.empty()
+			switch {
+			case len(inserted) == 0:
+			case len(inserted) == 1:
+				insert = inserted[0]
+			case new.Options().Balanced:
+				// the inserted nodes can be of different heights
+				for _, n := range inserted {
+					insert = new.concat(insert, n)
+				}
+				insert = new.root(insert)
+			default:
+				insert = new.parent(append([]Node[T](nil), inserted...))
+			}
+			result = append(result, trim(s.root, from, Edit[T]{offset, count, insert}))
+			offset, from = offset+insert.Count, from+count
+		}
+		if pair[1] < len(o.items) {
+			offset += o.items[pair[1]].Count
+			from += s.items[pair[0]].Count
+		}
+		last = pair
+	}
+	return result
+}
+
+// trim narrows e, which replaces the elements of old at offset, to
+// the elements that actually changed by dropping the prefix and the
+// suffix shared with old.  The elements are compared by hash, so
+// nothing is trimmed if the leaves do not implement Hasher.
+func trim[T any](old Node[T], offset int, e Edit[T]) Edit[T] {
+	size := min(e.Count, e.Insert.Count)
+	if size == 0 {
+		return e
+	}
+	region := old.Slice(offset, e.Count)
+	if _, ok := region.Hash(); !ok {
+		return e
+	}
+
+	prefix := sort.Search(size, func(kk int) bool {
+		return !Equal(region.Slice(0, kk+1), e.Insert.Slice(0, kk+1))
+	})
+	suffix := sort.Search(size-prefix, func(kk int) bool {
+		return !Equal(region.Slice(e.Count-kk-1, kk+1), e.Insert.Slice(e.Insert.Count-kk-1, kk+1))
+	})
+	if prefix+suffix > 0 {
+		e.Offset += prefix
+		e.Count -= prefix + suffix
+		e.Insert = e.Insert.Slice(prefix, e.Insert.Count-prefix-suffix)
+	}
+	return e
+}
+
+// increasing returns the longest subsequence of pairs (which are
+// ordered by the second element) that is also increasing in the
+// first element
+func increasing(pairs [][2]int) [][2]int {
+	tails, prev := []int{}, make([]int, len(pairs))
+	for kk, pair := range pairs {
+		pos := sort.Search(len(tails), func(ii int) bool {
+			return pairs[tails[ii]][0] >= pair[0]
+		})
+		prev[kk] = -1
+		if pos > 0 {
+			prev[kk] = tails[pos-1]
+		}
+		if pos == len(tails) {
+			tails = append(tails, kk)
+		} else {
+			tails[pos] = kk
+		}
+	}
+
+	result := make([][2]int, len(tails))
+	if len(tails) > 0 {
+		for kk, jj := len(tails)-1, tails[len(tails)-1]; kk >= 0; kk, jj = kk-1, prev[jj] {
+			result[kk] = pairs[jj]
+		}
+	}
+	return result
+}
+
+type diffItem[T any] struct {
+	Node[T]
+	side int
+}
+
+// diffQueue pops the largest nodes first, so that a node is only
+// expanded after all the nodes that could contain it on the other
+// side have been expanded
+type diffQueue[T any] []diffItem[T]
+
+func (q diffQueue[T]) Len() int      { return len(q) }
+func (q diffQueue[T]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q diffQueue[T]) Less(i, j int) bool {
+	if q[i].Count != q[j].Count {
+		return q[i].Count > q[j].Count
+	}
+	return q[i].height() > q[j].height()
+}
+
+func (q *diffQueue[T]) Push(x any) { *q = append(*q, x.(diffItem[T])) }
+func (q *diffQueue[T]) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
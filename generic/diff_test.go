@@ -0,0 +1,103 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	rand.Seed(42)
+	base := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < 200; kk++ {
+		base = base.Append(text(randomText(5)), 5)
+	}
+
+	for kk := 0; kk < 200; kk++ {
+		n := base
+		for edits := rand.Intn(4); edits >= 0; edits-- {
+			offset := rand.Intn(n.Count + 1)
+			count := rand.Intn(n.Count-offset+1) / (1 + rand.Intn(20))
+			insert := n.Slice(0, 0)
+			if rand.Intn(2) == 0 {
+				insert = base.Slice(rand.Intn(base.Count-10), rand.Intn(10))
+			}
+			n = n.Splice(offset, count, insert)
+		}
+
+		x := base
+		for _, e := range generic.Diff(base, n) {
+			x = x.Splice(e.Offset, e.Count, e.Insert)
+		}
+		if toString(x) != toString(n) {
+			t.Fatal("Diverged", toString(x), toString(n))
+		}
+	}
+}
+
+func TestDiffBalanced(t *testing.T) {
+	rand.Seed(42)
+	base := generic.New(text(""), 0).Balanced(2, 3)
+	for kk := 0; kk < 200; kk++ {
+		base = base.Append(text(randomText(5)), 5)
+	}
+
+	for kk := 0; kk < 200; kk++ {
+		n := base
+		for edits := rand.Intn(4); edits >= 0; edits-- {
+			offset := rand.Intn(n.Count + 1)
+			count := rand.Intn(n.Count-offset+1) / (1 + rand.Intn(20))
+			n = n.Splice(offset, count, base.Slice(rand.Intn(base.Count-100), rand.Intn(100)))
+		}
+
+		x := base
+		for _, e := range generic.Diff(base, n) {
+			x = x.Splice(e.Offset, e.Count, e.Insert)
+			checkBalanced(t, x, 2, 3, true)
+		}
+		if toString(x) != toString(n) {
+			t.Fatal("Diverged", toString(x), toString(n))
+		}
+	}
+}
+
+func TestDiffMinimal(t *testing.T) {
+	base := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < 1000; kk++ {
+		base = base.Append(text(randomText(5)), 5)
+	}
+
+	if edits := generic.Diff(base, base); len(edits) != 0 {
+		t.Fatal("Unexpected edits", edits)
+	}
+
+	n := base.Splice(1000, 5, generic.New(text("HELLO"), 5))
+	edits := generic.Diff(base, n)
+	if len(edits) != 1 || edits[0].Offset != 1000 || edits[0].Count != 5 || toString(edits[0].Insert) != "HELLO" {
+		t.Fatal("Unexpected edits", edits)
+	}
+
+	// an edit within a leaf only covers the elements that changed
+	leaf, _ := base.At(2001)
+	n = base.Splice(2000, 5, generic.New(leaf[:1]+"XY"+leaf[3:], 5))
+	edits = generic.Diff(base, n)
+	if len(edits) != 1 || edits[0].Offset != 2001 || edits[0].Count != 2 || toString(edits[0].Insert) != "XY" {
+		t.Fatal("Unexpected edits", edits)
+	}
+
+	n = base.Delete(100, 10).Insert(4000, "x", 1)
+	edits = generic.Diff(base, n)
+	if len(edits) != 2 || edits[0].Offset != 100 || edits[0].Count != 10 || edits[0].Insert.Count != 0 ||
+		edits[1].Offset != 4000 || edits[1].Count != 0 || toString(edits[1].Insert) != "x" {
+		t.Fatal("Unexpected edits", edits)
+	}
+
+	other := generic.New(text("other"), 5)
+	if edits := generic.Diff(base, other); len(edits) != 1 || edits[0].Count != base.Count {
+		t.Fatal("Unexpected edits", edits)
+	}
+}
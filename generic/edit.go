@@ -0,0 +1,250 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "sync"
+
+// spare tracks the unused capacity on either side of a Children
+// array.  Nodes sharing the array can claim the slot just outside
+// their children which lets Append and Prepend on the latest version
+// of a node skip copying the children.
+type spare[T any] struct {
+	sync.Mutex
+	all        []Node[T]
+	start, end int
+}
+
+// claim extends children by one slot at the front or the back if
+// that slot has not been claimed already
+func (s *spare[T]) claim(children []Node[T], front bool) ([]Node[T], bool) {
+	if s == nil || len(children) == 0 || cap(children) > len(s.all) {
+		return nil, false
+	}
+
+	start := len(s.all) - cap(children)
+	end := start + len(children)
+	if &s.all[start] != &children[0] {
+		return nil, false
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	switch {
+	case front && s.start == start && start > 0:
+		s.start--
+		return s.all[start-1 : end], true
+	case !front && s.end == end && end < len(s.all):
+		s.end++
+		return s.all[start : end+1], true
+	}
+	return nil, false
+}
+
+// Insert inserts count elements held in v at the provided offset.
+// The new leaf shares the ID space of n.
+func (n Node[T]) Insert(offset int, v T, count int) Node[T] {
+	switch offset {
+	case 0:
+		return n.Prepend(v, count)
+	case n.Count:
+		return n.Append(v, count)
+	}
+	return n.Splice(offset, 0, n.leaf(v, count))
+}
+
+// Delete removes count elements at the provided offset.
+func (n Node[T]) Delete(offset, count int) Node[T] {
+	return n.Splice(offset, count, n.Slice(0, 0))
+}
+
+// Append adds count elements held in v at the end.  Unlike Splice,
+// repeated appends to the latest version of a node take amortized
+// constant time.
+func (n Node[T]) Append(v T, count int) Node[T] {
+	return n.push(n.leaf(v, count), false)
+}
+
+// Prepend adds count elements held in v at the start.  Like Append,
+// this takes amortized constant time.
+func (n Node[T]) Prepend(v T, count int) Node[T] {
+	return n.push(n.leaf(v, count), true)
+}
+
+// Concat appends all the provided nodes in order.  Nodes of the same
+// ID space are added as is (without copying their children).  Nodes
+// from a different ID space are copied with new IDs as with Splice.
+func (n Node[T]) Concat(nodes ...Node[T]) Node[T] {
+	for _, o := range nodes {
+		n = n.push(o, false)
+	}
+	return n
+}
+
+// leaf creates a new leaf node sharing the settings of n
+func (n Node[T]) leaf(v T, count int) Node[T] {
+	return Node[T]{ids: n.ids, opts: n.opts, Leaf: v, Count: count}.fresh()
+}
+
+// push adds o as the first or last child of n
+func (n Node[T]) push(o Node[T], front bool) Node[T] {
+	offset := n.Count
+	if front {
+		offset = 0
+	}
+
+	o = n.adopt(o)
+	opts := n.Options()
+	switch {
+	case o.Count == 0:
+		return n
+	case n.Count == 0, n.Children == nil, opts.Balanced, opts.MinLeafSize > 0:
+		return n.Splice(offset, 0, o)
+	}
+
+	if len(n.Children) >= opts.MaxFanout {
+		n = n.compact(front, opts)
+	}
+	n = n.addChild(o, front)
+	if opts.FlattenDepth > 0 && n.depthAt(offset) > opts.FlattenDepth {
+		return n.Flatten(0)
+	}
+	return n
+}
+
+func (n Node[T]) addChild(o Node[T], front bool) Node[T] {
+	if children, ok := n.spare.claim(n.Children, front); ok {
+		if front {
+			children[0] = o
+		} else {
+			children[len(children)-1] = o
+		}
+		n.Children = children
+	} else {
+		n = n.grow(o, front)
+	}
+	n = n.fresh()
+	n.Count += o.Count
+	return n
+}
+
+// grow copies the children into a larger array with o added at the
+// front or the back and the rest of the array left spare.
+func (n Node[T]) grow(o Node[T], front bool) Node[T] {
+	size := len(n.Children) + 1
+	s := &spare[T]{all: make([]Node[T], 2*size)}
+	if front {
+		s.start, s.end = size, 2*size
+		s.all[s.start] = o
+		copy(s.all[s.start+1:], n.Children)
+	} else {
+		s.start, s.end = 0, size
+		copy(s.all, n.Children)
+		s.all[size-1] = o
+	}
+	n.Children = s.all[s.start:s.end]
+	n.spare = s
+	return n
+}
+
+// compact makes room in a full node by combining a pair of adjacent
+// children.  The pair of the least height wins, preferring pairs of
+// equal height and then the ones nearest to the back (or the front).
+// A pair of equal height is grouped into a new child along with the
+// rest of its run, much like carrying in a binary counter.  Otherwise
+// the pair is joined the way Balanced nodes are, so that the children
+// built up here stay balanced and the depth of the whole tree stays
+// logarithmic over long sequences of appends and prepends.
+func (n Node[T]) compact(front bool, opts Options) Node[T] {
+	heights := make([]int, len(n.Children))
+	for kk := range n.Children {
+		heights[kk] = n.Children[kk].height()
+	}
+
+	cost := func(kk int) (int, int) {
+		a, b := heights[kk], heights[kk+1]
+		return max(a, b), max(a-b, b-a)
+	}
+
+	best := -1
+	for kk := 0; kk+1 < len(heights); kk++ {
+		jj := len(heights) - 2 - kk
+		if front {
+			jj = kk
+		}
+		h, diff := cost(jj)
+		if bh, bdiff := cost(max(best, 0)); best < 0 || h < bh || h == bh && diff < bdiff {
+			best = jj
+		}
+	}
+
+	start, end := best, best+2
+	var merged Node[T]
+	if h := heights[best]; h == heights[best+1] {
+		for start > 0 && heights[start-1] == h {
+			start--
+		}
+		for end < len(heights) && heights[end] == h {
+			end++
+		}
+		merged = n.parent(n.Children[start:end:end])
+	} else {
+		balanced := n
+		balanced.opts = &opts
+		merged = balanced.concat(n.Children[best], n.Children[best+1])
+		merged.opts = n.opts
+	}
+
+	children := append([]Node[T](nil), n.Children[:start]...)
+	children = append(children, merged)
+	n.Children = append(children, n.Children[end:]...)
+	n.spare = nil
+	return n
+}
+
+// Insert inserts count elements held in v at the provided offset.
+// The value v must implement Splicer[T].
+func (h Hybrid[T]) Insert(offset int, v T, count int) Hybrid[T] {
+	if h.Node.Count > 0 {
+		h.Node = h.Node.Insert(offset, v, count)
+		return h
+	}
+	return h.Splice(offset, 0, h.wrap(v, count))
+}
+
+// Delete removes count elements at the provided offset.
+func (h Hybrid[T]) Delete(offset, count int) Hybrid[T] {
+	return h.Splice(offset, count, h.wrap(h.Raw.Slice(0, 0), 0))
+}
+
+// Append adds count elements held in v at the end.  The value v must
+// implement Splicer[T].
+func (h Hybrid[T]) Append(v T, count int) Hybrid[T] {
+	return h.Insert(h.Size(), v, count)
+}
+
+// Prepend adds count elements held in v at the start.  The value v
+// must implement Splicer[T].
+func (h Hybrid[T]) Prepend(v T, count int) Hybrid[T] {
+	return h.Insert(0, v, count)
+}
+
+// Concat appends all the provided hybrids in order.
+func (h Hybrid[T]) Concat(others ...Hybrid[T]) Hybrid[T] {
+	for _, o := range others {
+		switch {
+		case h.Node.Count == 0:
+			h = h.Splice(h.Count, 0, o)
+		case o.Node.Count > 0:
+			h.Node = h.Node.Concat(o.Node)
+		default:
+			h.Node = h.Node.Append(o.raw(), o.Count)
+		}
+	}
+	return h
+}
+
+func (h Hybrid[T]) wrap(v T, count int) Hybrid[T] {
+	return Hybrid[T]{h.HighMark, h.LowMark, splicer(v), count, Node[T]{}}
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func TestAppendPrepend(t *testing.T) {
+	rand.Seed(42)
+	n := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	str := ""
+	for kk := 0; kk < 5000; kk++ {
+		s := text(randomText(1 + rand.Intn(3)))
+		if rand.Intn(4) == 0 {
+			n = n.Prepend(s, len(s))
+			str = string(s) + str
+		} else {
+			n = n.Append(s, len(s))
+			str += string(s)
+		}
+	}
+
+	if x := toString(n); x != str {
+		t.Fatal("Diverged", x, str)
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatal("Invalid", err)
+	}
+	if s := n.Stats(); s.Depth > 20 || s.MaxChildren > 4 {
+		t.Fatal("Unexpected shape", s)
+	}
+}
+
+func TestAppendVersions(t *testing.T) {
+	n := generic.New(text("a"), 1).Append("b", 1).Append("c", 1)
+	x, y := n.Append("x", 1), n.Append("y", 1)
+	p, q := n.Prepend("p", 1), n.Prepend("q", 1)
+	xx := x.Append("x", 1)
+	for _, pair := range [][2]interface{}{
+		{n, "abc"}, {x, "abcx"}, {y, "abcy"}, {p, "pabc"}, {q, "qabc"}, {xx, "abcxx"},
+	} {
+		if s := toString(pair[0].(generic.Node[text])); s != pair[1] {
+			t.Fatal("Diverged", s, pair[1])
+		}
+	}
+}
+
+func TestInsertDelete(t *testing.T) {
+	n := generic.New(text("hello"), 5).Insert(5, " world", 6).Insert(0, ">", 1).Insert(6, ",", 1)
+	if x := toString(n); x != ">hello, world" {
+		t.Fatal("Insert", x)
+	}
+
+	if x := toString(n.Delete(0, 1).Delete(5, 2)); x != "helloworld" {
+		t.Fatal("Delete", x)
+	}
+
+	if x := toString(n.Delete(0, n.Count)); x != "" {
+		t.Fatal("Delete all", x)
+	}
+
+	mustPanic(t, func() { n.Insert(100, "x", 1) })
+	mustPanic(t, func() { n.Delete(10, 10) })
+}
+
+func TestConcat(t *testing.T) {
+	hello := generic.New(text("hello"), 5)
+	n := hello.Concat(hello.Slice(0, 0), generic.New(text(" "), 1), hello.Append(" world", 6))
+	if x := toString(n); x != "hello hello world" {
+		t.Fatal("Concat", x)
+	}
+
+	if x := toString(generic.New(text(""), 0).Concat(hello, hello)); x != "hellohello" {
+		t.Fatal("Concat empty", x)
+	}
+}
+
+func TestHybridEdits(t *testing.T) {
+	h := hybridRaw("hello").Append(" wo", 3).Prepend(">", 1)
+	if h.Node.Count != 0 || toStringH(h) != ">hello wo" {
+		t.Fatal("Raw edits", toStringH(h))
+	}
+
+	h = h.Append("rld", 3).Insert(6, ",", 1)
+	if h.Node.Count == 0 || toStringH(h) != ">hello, world" {
+		t.Fatal("Node edits", toStringH(h))
+	}
+
+	if x := h.Delete(0, 10); x.Node.Count != 0 || toStringH(x) != "rld" {
+		t.Fatal("Delete", toStringH(x))
+	}
+
+	c := hybridRaw("ab").Concat(hybridRaw("cd"), hybridRaw("").Splice(0, 0, hybridRaw("efghijklmno")), hybridRaw("p"))
+	if x := toStringH(c); x != "abcdefghijklmnop" {
+		t.Fatal("Concat", x)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LeafCodec converts leaf values to and from bytes for the Encoder
+// and Decoder.
+type LeafCodec[T any] interface {
+	MarshalLeaf(v T) ([]byte, error)
+	UnmarshalLeaf(data []byte) (T, error)
+}
+
+// GobCodec is a LeafCodec which uses encoding/gob.  It is what
+// MarshalBinary and UnmarshalBinary use.  Leaves of interface types
+// need their concrete types registered with gob.Register.
+type GobCodec[T any] struct{}
+
+// MarshalLeaf implements LeafCodec
+func (GobCodec[T]) MarshalLeaf(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&v)
+	return buf.Bytes(), err
+}
+
+// UnmarshalLeaf implements LeafCodec
+func (GobCodec[T]) UnmarshalLeaf(data []byte) (T, error) {
+	var v T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v)
+	return v, err
+}
+
+const (
+	tagLeaf = iota + 1
+	tagInternal
+	tagOptions
+	tagRoot
+	tagUID
+)
+
+// Encoder writes nodes to a stream.  Each node is written only once
+// per Encoder, so encoding many versions of a tree only writes the
+// nodes that changed between the versions.  All the nodes written by
+// an Encoder must come from the same Forest.
+type Encoder[T any] struct {
+	w     *bufio.Writer
+	codec LeafCodec[T]
+	ids   *IDSource
+	seen  map[int]bool
+	buf   []byte
+}
+
+// NewEncoder creates an Encoder which writes to w
+func NewEncoder[T any](w io.Writer, codec LeafCodec[T]) *Encoder[T] {
+	return &Encoder[T]{w: bufio.NewWriter(w), codec: codec, seen: map[int]bool{}}
+}
+
+// Encode writes the options of root, the nodes of root not written
+// before and then the ID of root itself.
+func (e *Encoder[T]) Encode(root Node[T]) error {
+	if e.ids == nil {
+		e.ids = root.ids
+	} else if e.ids != root.ids {
+		return errors.New("trope: cannot encode nodes from different ID spaces")
+	}
+
+	opts, isSet, balanced := root.Options(), int64(0), int64(0)
+	if root.opts != nil {
+		isSet = 1
+	}
+	if opts.Balanced {
+		balanced = 1
+	}
+	e.write(tagOptions, isSet, int64(opts.MaxFanout), int64(opts.MinFanout), balanced,
+		int64(opts.LeafSize), int64(opts.MinLeafSize), int64(opts.FlattenDepth))
+
+	if err := e.node(root); err != nil {
+		return err
+	}
+	e.write(tagRoot, int64(root.ID))
+	return e.w.Flush()
+}
+
+func (e *Encoder[T]) node(n Node[T]) error {
+	if e.seen[n.ID] {
+		return nil
+	}
+	e.seen[n.ID] = true
+
+	if uid := n.UID(); uid != plainUID(n.ID) {
+		e.write(tagUID, int64(n.ID), int64(binary.BigEndian.Uint64(uid[:8])), int64(binary.BigEndian.Uint64(uid[8:])))
+	}
+
+	if n.Children == nil {
+		n, err := n.Resolve()
+		if err != nil {
+			return err
+		}
+		data, err := e.codec.MarshalLeaf(n.Leaf)
+		if err != nil {
+			return err
+		}
+		e.write(tagLeaf, int64(n.ID), int64(n.Count), int64(len(data)))
+		_, err = e.w.Write(data)
+		return err
+	}
+
+	args := []int64{int64(n.ID), int64(n.Count), int64(len(n.Children))}
+	for _, child := range n.Children {
+		if err := e.node(child); err != nil {
+			return err
+		}
+		args = append(args, int64(child.ID))
+	}
+	e.write(tagInternal, args...)
+	return nil
+}
+
+func (e *Encoder[T]) write(tag int64, args ...int64) {
+	e.buf = binary.AppendVarint(e.buf[:0], tag)
+	for _, arg := range args {
+		e.buf = binary.AppendVarint(e.buf, arg)
+	}
+	e.w.Write(e.buf)
+}
+
+// Decoder reads nodes written by an Encoder.  The nodes decoded keep
+// their IDs (and UIDs) and belong to a single new Forest.  Nodes
+// shared between the encoded trees are shared between the decoded
+// trees too.
+type Decoder[T any] struct {
+	r     *bufio.Reader
+	codec LeafCodec[T]
+	ids   *IDSource
+	nodes map[int]Node[T]
+	opts  map[Options]*Options
+	cur   *Options
+}
+
+// NewDecoder creates a Decoder which reads from r
+func NewDecoder[T any](r io.Reader, codec LeafCodec[T]) *Decoder[T] {
+	return &Decoder[T]{
+		r:     bufio.NewReader(r),
+		codec: codec,
+		ids:   &IDSource{},
+		nodes: map[int]Node[T]{},
+		opts:  map[Options]*Options{},
+	}
+}
+
+// Decode reads the next root.  It returns io.EOF if there are no more
+// roots.
+func (d *Decoder[T]) Decode() (Node[T], error) {
+	for {
+		tag, err := binary.ReadVarint(d.r)
+		if err != nil {
+			return Node[T]{}, err
+		}
+
+		switch tag {
+		case tagLeaf:
+			err = d.leaf()
+		case tagInternal:
+			err = d.internal()
+		case tagOptions:
+			err = d.options()
+		case tagRoot:
+			return d.root()
+		case tagUID:
+			err = d.uid()
+		default:
+			err = fmt.Errorf("trope: unexpected tag %d", tag)
+		}
+		if err != nil {
+			return Node[T]{}, unexpectedEOF(err)
+		}
+	}
+}
+
+func (d *Decoder[T]) leaf() error {
+	args, err := d.read(3)
+	if err != nil {
+		return err
+	}
+	if args[1] < 0 {
+		return errors.New("trope: invalid count")
+	}
+	if args[2] < 0 {
+		return errors.New("trope: invalid leaf size")
+	}
+	data, err := io.ReadAll(io.LimitReader(d.r, args[2]))
+	if err == nil && int64(len(data)) != args[2] {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	v, err := d.codec.UnmarshalLeaf(data)
+	if err != nil {
+		return err
+	}
+	if l, ok := any(v).(Lener); ok && int64(l.Len()) != args[1] {
+		return errors.New("trope: invalid count")
+	}
+	d.add(Node[T]{ID: int(args[0]), ids: d.ids, opts: d.cur, Leaf: v, Count: int(args[1])}.withCache())
+	return nil
+}
+
+func (d *Decoder[T]) internal() error {
+	args, err := d.read(3)
+	if err != nil {
+		return err
+	}
+	if args[2] < 0 || args[2] > max(args[1], 0) {
+		return errors.New("trope: invalid number of children")
+	}
+	childIDs, err := d.read(int(args[2]))
+	if err != nil {
+		return err
+	}
+	children := make([]Node[T], len(childIDs))
+	total := int64(0)
+	for kk, id := range childIDs {
+		child, ok := d.nodes[int(id)]
+		if !ok {
+			return fmt.Errorf("trope: unknown node %d", id)
+		}
+		children[kk] = child
+		total += int64(child.Count)
+	}
+	if total != args[1] {
+		return errors.New("trope: invalid count")
+	}
+	d.add(Node[T]{ID: int(args[0]), ids: d.ids, opts: d.cur, Children: children, Count: int(args[1])}.withCache())
+	return nil
+}
+
+func (d *Decoder[T]) options() error {
+	args, err := d.read(7)
+	if err != nil {
+		return err
+	}
+	if args[0] == 0 {
+		d.cur = nil
+		return nil
+	}
+
+	opts := Options{
+		MaxFanout:    int(args[1]),
+		MinFanout:    int(args[2]),
+		Balanced:     args[3] != 0,
+		LeafSize:     int(args[4]),
+		MinLeafSize:  int(args[5]),
+		FlattenDepth: int(args[6]),
+	}
+	if d.opts[opts] == nil {
+		d.opts[opts] = &opts
+	}
+	d.cur = d.opts[opts]
+	return nil
+}
+
+func (d *Decoder[T]) root() (Node[T], error) {
+	args, err := d.read(1)
+	if err != nil {
+		return Node[T]{}, unexpectedEOF(err)
+	}
+	root, ok := d.nodes[int(args[0])]
+	if !ok {
+		return Node[T]{}, fmt.Errorf("trope: unknown node %d", args[0])
+	}
+	root.opts = d.cur
+	return root, nil
+}
+
+func (d *Decoder[T]) uid() error {
+	args, err := d.read(3)
+	if err != nil {
+		return err
+	}
+	var uid UID
+	binary.BigEndian.PutUint64(uid[:8], uint64(args[1]))
+	binary.BigEndian.PutUint64(uid[8:], uint64(args[2]))
+	d.ids.restore(int(args[0]), uid)
+	return nil
+}
+
+func (d *Decoder[T]) add(n Node[T]) {
+	d.ids.reserve(n.ID)
+	d.nodes[n.ID] = n
+}
+
+func (d *Decoder[T]) read(count int) ([]int64, error) {
+	result := make([]int64, count)
+	for kk := range result {
+		v, err := binary.ReadVarint(d.r)
+		if err != nil {
+			return nil, err
+		}
+		result[kk] = v
+	}
+	return result, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using GobCodec
+// for the leaves.
+func (n Node[T]) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	err := NewEncoder[T](&buf, GobCodec[T]{}).Encode(n)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler using
+// GobCodec for the leaves.
+func (n *Node[T]) UnmarshalBinary(data []byte) error {
+	root, err := NewDecoder[T](bytes.NewReader(data), GobCodec[T]{}).Decode()
+	if err == nil {
+		*n = root
+	}
+	return unexpectedEOF(err)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using GobCodec
+// for the leaves.  It preserves the marks and whether h is in Raw
+// mode.
+func (h Hybrid[T]) MarshalBinary() ([]byte, error) {
+	n, raw := h.Node, int64(0)
+	if h.Node.Count == 0 {
+		n, raw = New(h.raw(), h.Count), 1
+	}
+
+	empty, err := GobCodec[T]{}.MarshalLeaf(h.Raw.Slice(0, 0))
+	if err != nil {
+		return nil, err
+	}
+	data, err := n.MarshalBinary()
+	buf := binary.AppendVarint(nil, int64(h.HighMark))
+	buf = binary.AppendVarint(buf, int64(h.LowMark))
+	buf = binary.AppendVarint(buf, raw)
+	buf = binary.AppendVarint(buf, int64(len(empty)))
+	return append(append(buf, empty...), data...), err
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler using
+// GobCodec for the leaves.
+func (h *Hybrid[T]) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var args [4]int64
+	for kk := range args {
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		args[kk] = v
+	}
+	if args[3] < 0 || args[3] > int64(r.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+
+	empty := make([]byte, args[3])
+	r.Read(empty)
+	v, err := GobCodec[T]{}.UnmarshalLeaf(empty)
+	if err != nil {
+		return err
+	}
+
+	var n Node[T]
+	if err := n.UnmarshalBinary(data[len(data)-r.Len():]); err != nil {
+		return err
+	}
+	*h = Hybrid[T]{int(args[0]), int(args[1]), splicer(v), 0, n}
+	if args[2] != 0 {
+		*h = Hybrid[T]{h.HighMark, h.LowMark, splicer(n.Leaf), n.Count, Node[T]{}}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/perdata/trope/generic"
+	"io"
+	"testing"
+)
+
+type textCodec struct{}
+
+func (textCodec) MarshalLeaf(v text) ([]byte, error)      { return []byte(v), nil }
+func (textCodec) UnmarshalLeaf(data []byte) (text, error) { return text(data), nil }
+
+func TestMarshalBinary(t *testing.T) {
+	n := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4, LeafSize: 8})
+	for kk := 0; kk < 50; kk++ {
+		n = n.Append(text(randomText(5)), 5)
+	}
+	n = n.Splice(10, 0, n.Slice(20, 30))
+
+	data, err := n.MarshalBinary()
+	if err != nil {
+		t.Fatal("Marshal", err)
+	}
+	var m generic.Node[text]
+	if err := m.UnmarshalBinary(data); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+	if toString(m) != toString(n) || m.ID != n.ID || m.Options() != n.Options() || m.Stats() != n.Stats() {
+		t.Fatal("Diverged", toString(m), toString(n))
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal("Invalid", err)
+	}
+	if x := m.Append("!", 1); x.Validate() != nil {
+		t.Fatal("Invalid after edit", x.Validate())
+	}
+
+	for kk := 0; kk < len(data); kk++ {
+		if err := m.UnmarshalBinary(data[:kk]); err == nil {
+			t.Fatal("Unexpected success with truncated data", kk)
+		}
+	}
+}
+
+func TestEncoderSharing(t *testing.T) {
+	base := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 8})
+	for kk := 0; kk < 1000; kk++ {
+		base = base.Append(text(randomText(10)), 10)
+	}
+
+	var single bytes.Buffer
+	if err := generic.NewEncoder[text](&single, textCodec{}).Encode(base); err != nil {
+		t.Fatal("Encode", err)
+	}
+
+	versions := []generic.Node[text]{base}
+	for kk := 1; kk < 100; kk++ {
+		last := versions[kk-1]
+		versions = append(versions, last.Splice(kk*37, 3, last.Forest().New("abc", 3)))
+	}
+	var buf bytes.Buffer
+	enc := generic.NewEncoder[text](&buf, textCodec{})
+	for _, v := range versions {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal("Encode", err)
+		}
+	}
+	if buf.Len() > 2*single.Len() {
+		t.Fatal("Unexpected size", buf.Len(), single.Len())
+	}
+
+	dec := generic.NewDecoder[text](&buf, textCodec{})
+	var decoded []generic.Node[text]
+	for {
+		v, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Decode", err)
+		}
+		decoded = append(decoded, v)
+	}
+	if len(decoded) != len(versions) {
+		t.Fatal("Unexpected count", len(decoded))
+	}
+	for kk, v := range decoded {
+		if toString(v) != toString(versions[kk]) {
+			t.Fatal("Diverged", kk)
+		}
+		if kk > 0 && len(generic.Diff(decoded[kk-1], v)) != 1 {
+			t.Fatal("Unexpected diff", generic.Diff(decoded[kk-1], v))
+		}
+	}
+
+	if err := enc.Encode(generic.New(text("x"), 1)); err == nil {
+		t.Fatal("Unexpected success with a different ID space")
+	}
+}
+
+func TestHybridMarshalBinary(t *testing.T) {
+	for _, h := range []generic.Hybrid[text]{hybridRaw("hello"), hybridRaw("hello").Append(" world", 6)} {
+		data, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal("Marshal", err)
+		}
+		var x generic.Hybrid[text]
+		if err := x.UnmarshalBinary(data); err != nil {
+			t.Fatal("Unmarshal", err)
+		}
+		if toStringH(x) != toStringH(h) || (x.Node.Count == 0) != (h.Node.Count == 0) || x.HighMark != h.HighMark {
+			t.Fatal("Diverged", toStringH(x), toStringH(h))
+		}
+		if x = x.Delete(0, 5); toStringH(x) != toStringH(h)[5:] {
+			t.Fatal("Diverged after edit", toStringH(x))
+		}
+	}
+}
+
+func TestUIDRoundTrip(t *testing.T) {
+	for _, gen := range []generic.IDGenerator{generic.RandomIDs(), generic.TimeOrderedIDs(), generic.Counter(7)} {
+		f := generic.NewForestWithIDs[text](generic.NewIDSource(gen))
+		n := f.New("hello", 5).Append(" world", 6)
+
+		var binary, nested generic.Node[text]
+		data, err := n.MarshalBinary()
+		if err == nil {
+			err = binary.UnmarshalBinary(data)
+		}
+		if err != nil {
+			t.Fatal("Binary", err)
+		}
+		data, err = n.MarshalJSONWith(generic.JSONOptions{Nested: true})
+		if err == nil {
+			err = json.Unmarshal(data, &nested)
+		}
+		if err != nil {
+			t.Fatal("JSON", err)
+		}
+
+		for _, m := range []generic.Node[text]{binary, nested} {
+			uids := map[generic.UID]bool{n.UID(): true}
+			if m.UID() != n.UID() {
+				t.Fatal("Unexpected UID", m.UID(), n.UID())
+			}
+			for kk, child := range m.Children {
+				if child.UID() != n.Children[kk].UID() {
+					t.Fatal("Unexpected child UID", child.UID(), n.Children[kk].UID())
+				}
+				uids[child.UID()] = true
+			}
+
+			edited := m.Append("!", 1)
+			if uids[edited.UID()] || uids[edited.Children[len(edited.Children)-1].UID()] || edited.Validate() != nil {
+				t.Fatal("Unexpected UID after edit", edited.UID())
+			}
+		}
+	}
+}
+
+func TestDecoderCounts(t *testing.T) {
+	stream := func(args ...int64) []byte {
+		var buf []byte
+		for _, arg := range args {
+			buf = binary.AppendVarint(buf, arg)
+		}
+		return buf
+	}
+	leaf := func(id, count int64) []byte {
+		return append(stream(1, id, count, 3), "abc"...)
+	}
+
+	// tags: 1 = leaf, 2 = internal, 4 = root
+	valid := append(leaf(2, 3), stream(2, 1, 3, 1, 2, 4, 1)...)
+	if n, err := generic.NewDecoder[text](bytes.NewReader(valid), textCodec{}).Decode(); err != nil || toString(n) != "abc" {
+		t.Fatal("Decode", toString(n), err)
+	}
+
+	for _, data := range [][]byte{
+		append(leaf(1, -4), stream(4, 1)...),
+		append(leaf(1, 5), stream(4, 1)...),
+		append(leaf(2, 3), stream(2, 1, 10, 1, 2, 4, 1)...),
+	} {
+		if _, err := generic.NewDecoder[text](bytes.NewReader(data), textCodec{}).Decode(); err == nil {
+			t.Fatal("Unexpected success", data)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "fmt"
+
+// RangeError is returned when the offset and count do not fit within
+// a Node or Hybrid of the specified size.
+type RangeError struct {
+	Offset, Count, Size int
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("trope: offset %d, count %d out of range for size %d", e.Offset, e.Count, e.Size)
+}
+
+func checkRange(offset, count, size int) error {
+	if offset < 0 || count < 0 || offset > size || count > size-offset {
+		return &RangeError{offset, count, size}
+	}
+	return nil
+}
+
+// TrySlice is like Slice but it returns a *RangeError instead of
+// panicking if the offset or count are out of range.
+func (n Node[T]) TrySlice(offset, count int) (Node[T], error) {
+	if err := checkRange(offset, count, n.Count); err != nil {
+		return n, err
+	}
+	return n.Slice(offset, count), nil
+}
+
+// TrySplice is like Splice but it returns a *RangeError instead of
+// panicking if the offset or count are out of range.
+func (n Node[T]) TrySplice(offset, count int, replacement Node[T]) (Node[T], error) {
+	if err := checkRange(offset, count, n.Count); err != nil {
+		return n, err
+	}
+	return n.Splice(offset, count, replacement), nil
+}
+
+// TrySlice is like Slice but it returns a *RangeError instead of
+// panicking if the offset or count are out of range.
+func (h Hybrid[T]) TrySlice(offset, count int) (Hybrid[T], error) {
+	if err := checkRange(offset, count, h.Size()); err != nil {
+		return h, err
+	}
+	return h.Slice(offset, count), nil
+}
+
+// TrySplice is like Splice but it returns a *RangeError instead of
+// panicking if the offset or count are out of range.
+func (h Hybrid[T]) TrySplice(offset, count int, replacement Hybrid[T]) (Hybrid[T], error) {
+	if err := checkRange(offset, count, h.Size()); err != nil {
+		return h, err
+	}
+	return h.Splice(offset, count, replacement), nil
+}
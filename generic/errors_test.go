@@ -0,0 +1,57 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"errors"
+	"github.com/perdata/trope/generic"
+	"math"
+	"testing"
+)
+
+func TestTrySliceSplice(t *testing.T) {
+	n := generic.New(text("hello"), 5)
+	h := hybridRaw("hello")
+	replace := generic.New(text("j"), 1)
+
+	invalid := [][2]int{{-1, 4}, {1, -2}, {3, 20}, {6, 0}, {1, math.MaxInt}}
+	for _, args := range invalid {
+		offset, count := args[0], args[1]
+		expected := generic.RangeError{Offset: offset, Count: count, Size: 5}
+		errs := []error{}
+		_, err := n.TrySlice(offset, count)
+		errs = append(errs, err)
+		_, err = n.TrySplice(offset, count, replace)
+		errs = append(errs, err)
+		_, err = h.TrySlice(offset, count)
+		errs = append(errs, err)
+		_, err = h.TrySplice(offset, count, hybridRaw("j"))
+		errs = append(errs, err)
+
+		for _, err := range errs {
+			var rerr *generic.RangeError
+			if !errors.As(err, &rerr) || *rerr != expected {
+				t.Fatal("Unexpected error", args, err)
+			}
+		}
+
+		mustPanic(t, func() { n.Splice(offset, count, replace) })
+		mustPanic(t, func() { h.Splice(offset, count, hybridRaw("j")) })
+		mustPanic(t, func() { h.Slice(offset, count) })
+	}
+
+	if x, err := n.TrySplice(0, 1, replace); err != nil || toString(x) != "jello" {
+		t.Fatal("TrySplice", x, err)
+	}
+	if x, err := n.TrySlice(1, 4); err != nil || toString(x) != "ello" {
+		t.Fatal("TrySlice", x, err)
+	}
+	if x, err := h.TrySplice(0, 1, hybridRaw("j")); err != nil || toStringH(x) != "jello" {
+		t.Fatal("Hybrid TrySplice", x, err)
+	}
+	if x, err := h.TrySlice(5, 0); err != nil || toStringH(x) != "" {
+		t.Fatal("Hybrid TrySlice", x, err)
+	}
+}
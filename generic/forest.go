@@ -0,0 +1,74 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+// Forest creates nodes that share a single ID space.  Nodes created
+// by the same forest (and all the nodes derived from them) can be
+// spliced into each other and still keep their IDs unique.
+type Forest[T any] struct {
+	ids *IDSource
+}
+
+// NewForest creates a forest with a new ID space
+func NewForest[T any]() Forest[T] {
+	return Forest[T]{&IDSource{}}
+}
+
+// NewForestWithIDs creates a forest using the provided ID source.
+// Forests of different element types can share a source.
+func NewForestWithIDs[T any](ids *IDSource) Forest[T] {
+	return Forest[T]{ids}
+}
+
+// Forest returns the forest n belongs to.  Nodes created via the
+// result can be spliced into n without being assigned new IDs.
+func (n Node[T]) Forest() Forest[T] {
+	return Forest[T]{n.ids}
+}
+
+// IDSource returns the source of the IDs of the nodes in the forest
+func (f Forest[T]) IDSource() *IDSource {
+	return f.ids
+}
+
+// New is like the package-level New but the ID of the node comes from
+// the forest.
+func (f Forest[T]) New(initial T, count int) Node[T] {
+	return Node[T]{ids: f.ids, Leaf: initial, Count: count}.fresh()
+}
+
+// NewWithOptions is like the package-level NewWithOptions but the ID
+// of the node comes from the forest.
+func (f Forest[T]) NewWithOptions(initial T, count int, opts Options) Node[T] {
+	return f.New(initial, count).WithOptions(opts)
+}
+
+// adopt returns o with new IDs from the ID space of n if o comes from
+// a different ID space.  Nodes that appear more than once within o
+// continue to be shared.
+func (n Node[T]) adopt(o Node[T]) Node[T] {
+	if o.ids == n.ids || o.Count == 0 {
+		return o
+	}
+	return n.adoptNode(o, map[int][2]Node[T]{})
+}
+
+func (n Node[T]) adoptNode(o Node[T], seen map[int][2]Node[T]) Node[T] {
+	if pair, ok := seen[o.ID]; ok && pair[0].same(o) {
+		return pair[1]
+	}
+
+	result := o
+	result.ids, result.spare = n.ids, nil
+	result = result.fresh()
+	if o.Children != nil {
+		result.Children = make([]Node[T], len(o.Children))
+		for kk, child := range o.Children {
+			result.Children[kk] = n.adoptNode(child, seen)
+		}
+	}
+	seen[o.ID] = [2]Node[T]{o, result}
+	return result
+}
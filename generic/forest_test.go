@@ -0,0 +1,73 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"testing"
+)
+
+func TestGraftedIDs(t *testing.T) {
+	doc := generic.New(text("hello"), 5).Append(" world", 6)
+	other := generic.New(text("<"), 1).Append(">", 1)
+
+	for _, n := range []generic.Node[text]{
+		doc.Splice(5, 0, other),
+		doc.Splice(0, doc.Count, other),
+		doc.Splice(11, 0, other),
+		doc.Concat(other, other),
+		doc.Balanced(2, 3).Splice(3, 2, other),
+	} {
+		if err := n.Validate(); err != nil {
+			t.Fatal("Invalid", toString(n), err)
+		}
+	}
+
+	// empty replacements from another ID space do not bring it along
+	empty := generic.New(text(""), 0)
+	for _, n := range []generic.Node[text]{
+		doc.Splice(0, doc.Count, empty),
+		doc.Slice(0, 0).Splice(0, 0, empty),
+		doc.Balanced(2, 3).Splice(0, doc.Count, empty),
+	} {
+		if n.Count != 0 || n.Forest() != doc.Forest() {
+			t.Fatal("Unexpected ID space", toString(n))
+		}
+		if edits := generic.Diff(doc, n.Concat(doc)); len(edits) != 0 {
+			t.Fatal("Unexpected edits", edits)
+		}
+	}
+
+	// shared subtrees of the replacement stay shared
+	twice := other.Concat(other)
+	if err := doc.Splice(5, 0, twice).Validate(); err != nil {
+		t.Fatal("Invalid", err)
+	}
+}
+
+func TestForest(t *testing.T) {
+	f := generic.NewForest[text]()
+	doc := f.New("hello", 5)
+	world := f.New(" world", 6)
+
+	n := doc.Splice(5, 0, world)
+	if x := toString(n); x != "hello world" {
+		t.Fatal("Diverged", x)
+	}
+	if n.Children[1].ID != world.ID {
+		t.Fatal("Unexpected new ID", n.Children[1].ID, world.ID)
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatal("Invalid", err)
+	}
+
+	n = n.Append("!", 1).Splice(0, 0, n.Forest().New("!", 1))
+	if err := n.Validate(); err != nil {
+		t.Fatal("Invalid", err)
+	}
+	if n.Forest().IDSource() != f.IDSource() {
+		t.Fatal("Unexpected ID source")
+	}
+}
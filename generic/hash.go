@@ -0,0 +1,175 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "math/bits"
+
+// Hasher is an optional interface to be implemented by the leaf-node
+// values to support Node.Hash and Equal.  The hash of a leaf must be
+// the combination of the hashes of its elements (as with HashString,
+// HashBytes or by appending HashElement values) so that the hash of
+// a node does not depend on how the elements are split into leaves.
+type Hasher interface {
+	Hash() Hash
+}
+
+// Hash is a content hash of a sequence of elements.  It is a pair of
+// polynomial hashes modulo 2^61-1 along with the number of elements.
+// Appending hashes matches hashing the concatenated sequence.
+type Hash struct {
+	Sum   [2]uint64
+	Count int
+}
+
+const hashPrime = 1<<61 - 1
+
+var hashBases = [2]uint64{0x1a2b3c4d5e6f789 % hashPrime, 0x0fedcba987654321 % hashPrime}
+
+// HashElement returns the hash of a single element with the provided
+// value.
+func HashElement(v uint64) Hash {
+	// splitmix64 finalizer
+	v += 0x9e3779b97f4a7c15
+	v = (v ^ v>>30) * 0xbf58476d1ce4e5b9
+	v = (v ^ v>>27) * 0x94d049bb133111eb
+	v ^= v >> 31
+	return Hash{[2]uint64{v % hashPrime, (v >> 3) % hashPrime}, 1}
+}
+
+// HashBytes hashes the bytes as individual elements
+func HashBytes(b []byte) Hash {
+	var h Hash
+	for _, c := range b {
+		h = h.Append(byteHashes[c])
+	}
+	return h
+}
+
+// HashString hashes the bytes of the string as individual elements
+func HashString(s string) Hash {
+	var h Hash
+	for kk := 0; kk < len(s); kk++ {
+		h = h.Append(byteHashes[s[kk]])
+	}
+	return h
+}
+
+var byteHashes = func() (result [256]Hash) {
+	for kk := range result {
+		result[kk] = HashElement(uint64(kk))
+	}
+	return result
+}()
+
+// Append returns the hash of the elements of h followed by those of o
+func (h Hash) Append(o Hash) Hash {
+	for kk, base := range hashBases {
+		h.Sum[kk] = addmod(mulmod(h.Sum[kk], powmod(base, o.Count)), o.Sum[kk])
+	}
+	h.Count += o.Count
+	return h
+}
+
+func addmod(a, b uint64) uint64 {
+	if a += b; a >= hashPrime {
+		a -= hashPrime
+	}
+	return a
+}
+
+func mulmod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// a*b = hi*2^64 + lo and 2^61 = 1 (mod p)
+	r := (hi<<3 | lo>>61) + lo&hashPrime
+	for r >= hashPrime {
+		r -= hashPrime
+	}
+	return r
+}
+
+func powmod(base uint64, exp int) uint64 {
+	result := uint64(1)
+	for ; exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			result = mulmod(result, base)
+		}
+		base = mulmod(base, base)
+	}
+	return result
+}
+
+// hashKey is the key the hash of a node is stored with.  Nodes with
+// leaves that do not implement Hasher store nil.
+type hashKey struct{}
+
+// Hash returns the content hash of the node.  It returns false if any
+// of the leaves does not implement Hasher.  The hash is stored on the
+// node once computed, so hashing nodes derived from a hashed node
+// only visits the nodes that changed.  It panics if a leaf loaded by
+// a NodeStore cannot be read.
+func (n Node[T]) Hash() (Hash, bool) {
+	if n.Count == 0 {
+		return Hash{}, true
+	}
+
+	if v, ok := n.load(hashKey{}); ok {
+		h, ok := v.(Hash)
+		return h, ok
+	}
+
+	result, ok := n.hash()
+	if ok {
+		n.store(hashKey{}, result)
+	} else {
+		n.store(hashKey{}, nil)
+	}
+	return result, ok
+}
+
+func (n Node[T]) hash() (Hash, bool) {
+	if n.Children == nil {
+		h, ok := any(n.value()).(Hasher)
+		if !ok {
+			return Hash{}, false
+		}
+		return h.Hash(), true
+	}
+
+	var result Hash
+	for _, child := range n.Children {
+		h, ok := child.Hash()
+		if !ok {
+			return Hash{}, false
+		}
+		result = result.Append(h)
+	}
+	return result, true
+}
+
+// Equal checks if a and b have the same elements.  It compares the
+// hashes of a and b (see Node.Hash), so the leaves must implement
+// Hasher.  Otherwise, Equal only reports true for the same node.
+func Equal[T any](a, b Node[T]) bool {
+	if a.Count != b.Count {
+		return false
+	}
+	if a.ids == b.ids && a.ID == b.ID && a.same(b) {
+		return true
+	}
+	ha, ok := a.Hash()
+	if !ok {
+		return false
+	}
+	hb, ok := b.Hash()
+	return ok && ha == hb
+}
+
+// Hash returns the content hash of the elements of h
+func (h Hybrid[T]) Hash() (Hash, bool) {
+	if h.Node.Count == 0 {
+		return New(h.raw(), h.Count).Hash()
+	}
+	return h.Node.Hash()
+}
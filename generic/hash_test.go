@@ -0,0 +1,138 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func (s text) Hash() generic.Hash {
+	return generic.HashString(string(s))
+}
+
+func TestHashShape(t *testing.T) {
+	rand.Seed(42)
+	str := randomText(1000)
+
+	a := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < len(str); kk += 10 {
+		a = a.Append(text(str[kk:kk+10]), 10)
+	}
+	b := generic.New(text(""), 0)
+	for kk := len(str); kk > 0; {
+		size := min(kk, 1+rand.Intn(30))
+		b = b.Prepend(text(str[kk-size:kk]), size)
+		kk -= size
+	}
+
+	ha, ok := a.Hash()
+	if !ok || ha != generic.HashString(str) || ha.Count != len(str) {
+		t.Fatal("Unexpected hash", ha, ok)
+	}
+	if hb, _ := b.Hash(); hb != ha || !generic.Equal(a, b) {
+		t.Fatal("Hash depends on shape", ha, hb)
+	}
+
+	for kk := 0; kk < 100; kk++ {
+		offset := rand.Intn(len(str))
+		c := b.Splice(offset, 1, generic.New(text("\x00"), 1))
+		if generic.Equal(a, c) != (str[offset] == 0) {
+			t.Fatal("Unexpected Equal", offset)
+		}
+		if !generic.Equal(c.Splice(offset, 1, a.Slice(offset, 1)), a) {
+			t.Fatal("Unexpected inequality", offset)
+		}
+	}
+	if generic.Equal(a, a.Slice(1, a.Count-1)) {
+		t.Fatal("Unexpected Equal")
+	}
+}
+
+func TestHashAppend(t *testing.T) {
+	str := "hello world"
+	for kk := 0; kk <= len(str); kk++ {
+		h := generic.HashString(str[:kk]).Append(generic.HashBytes([]byte(str[kk:])))
+		if h != generic.HashString(str) {
+			t.Fatal("Unexpected hash", kk)
+		}
+	}
+	if generic.HashString("ab") == generic.HashString("ba") {
+		t.Fatal("Unexpected collision")
+	}
+	if generic.HashString("") != (generic.Hash{}) {
+		t.Fatal("Unexpected empty hash")
+	}
+}
+
+func TestHashUnsupported(t *testing.T) {
+	a := generic.New([]int{1, 2}, 2).Append([]int{3}, 1)
+	if _, ok := a.Hash(); ok {
+		t.Fatal("Unexpected hash")
+	}
+	if !generic.Equal(a, a) || generic.Equal(a, a.Slice(0, 2).Append([]int{3}, 1)) {
+		t.Fatal("Unexpected Equal")
+	}
+}
+
+func TestHybridHash(t *testing.T) {
+	h := hybridRaw("hello")
+	n := generic.New(text("hello"), 5)
+	if hh, ok := h.Hash(); !ok || hh != generic.HashString("hello") {
+		t.Fatal("Unexpected hash", hh, ok)
+	}
+	if hh, _ := h.Insert(5, text(" world"), 6).Hash(); hh != generic.HashString("hello world") {
+		t.Fatal("Unexpected hash", hh)
+	}
+	if hn, _ := n.Hash(); hn != generic.HashString("hello") {
+		t.Fatal("Unexpected hash", hn)
+	}
+}
+
+// hashCounted counts the calls to Hash
+type hashCounted string
+
+var hashCalls int
+
+func (s hashCounted) Hash() generic.Hash {
+	hashCalls++
+	return generic.HashString(string(s))
+}
+
+func (s hashCounted) Slice(offset, count int) hashCounted {
+	return s[offset : offset+count]
+}
+
+func TestHashStored(t *testing.T) {
+	n := generic.New(hashCounted(""), 0)
+	for kk := 0; kk < 80000; kk++ {
+		n = n.Append("ab", 2)
+	}
+	h, _ := n.Hash()
+
+	for kk := 0; kk < 100; kk++ {
+		hashCalls = 0
+		offset := 2 * rand.Intn(n.Count/2)
+		x := n.Splice(offset, 2, generic.New(hashCounted("ab"), 2))
+		if hx, _ := x.Hash(); hx != h || !generic.Equal(x, n) || hashCalls > 1 {
+			t.Fatal("Unexpected hash", hashCalls)
+		}
+		if generic.Equal(n, x.Slice(0, x.Count-1).Append("a", 1)) || hashCalls > 3 {
+			t.Fatal("Unexpected Equal", hashCalls)
+		}
+	}
+}
+
+func TestHashChangedCount(t *testing.T) {
+	// a node changed in place is not supported, but a changed Count
+	// is at least not given the stored hash
+	a := generic.New(text("hello"), 5)
+	a.Hash()
+	a.Leaf, a.Count = "hello!", 6
+	if h, _ := a.Hash(); h != generic.HashString("hello!") {
+		t.Fatal("Unexpected stored hash", h)
+	}
+}
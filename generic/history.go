@@ -0,0 +1,179 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "time"
+
+// Document is implemented by both Node[T] and Hybrid[T]
+type Document[D any] interface {
+	Splice(offset, count int, replacement D) D
+	Size() int
+}
+
+// HistoryOptions control how History groups edits and how much it
+// remembers.  The zero value groups nothing and remembers everything.
+type HistoryOptions struct {
+	// GroupWithin is the time within which consecutive edits with
+	// the same label are undone together.
+	GroupWithin time.Duration
+
+	// MaxSteps is the maximum number of undo steps kept.
+	MaxSteps int
+
+	// MaxBytes limits the estimated memory used by the undo and
+	// redo steps.  Each step is estimated to use ElementSize bytes
+	// for every element deleted or inserted by it.
+	MaxBytes, ElementSize int
+
+	// Now returns the time of an edit.  It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Entry describes a single edit recorded by History
+type Entry struct {
+	Label string
+	Time  time.Time
+}
+
+// History tracks the versions of a document (a Node or a Hybrid) to
+// provide undo and redo.  As old versions remain valid, each step
+// simply remembers the document before and after it.
+type History[D Document[D]] struct {
+	opts       HistoryOptions
+	current    D
+	undo, redo []step[D]
+	bytes      int
+	grouping   bool
+}
+
+type step[D any] struct {
+	before, after D
+	entries       []Entry
+	bytes         int
+}
+
+// NewHistory creates a history starting with the provided document
+func NewHistory[D Document[D]](initial D, opts HistoryOptions) *History[D] {
+	if opts.ElementSize <= 0 {
+		opts.ElementSize = 1
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &History[D]{opts: opts, current: initial}
+}
+
+// Current returns the current version of the document
+func (h *History[D]) Current() D {
+	return h.current
+}
+
+// Splice edits the current version and records the edit.  It returns
+// the new version.
+func (h *History[D]) Splice(offset, count int, replacement D, label string) D {
+	h.record(h.current.Splice(offset, count, replacement), label, count+replacement.Size())
+	return h.current
+}
+
+// Set records an arbitrary new version of the document, such as one
+// created by calling Splice directly or by applying a Delta.  As the
+// extent of the change is not known, the step is estimated to replace
+// the whole document.
+func (h *History[D]) Set(version D, label string) {
+	h.record(version, label, max(version.Size(), h.current.Size()))
+}
+
+func (h *History[D]) record(version D, label string, changed int) {
+	entry := Entry{label, h.opts.Now()}
+	h.drop(h.redo)
+	h.redo = nil
+
+	last := len(h.undo) - 1
+	if h.grouping && last >= 0 && h.groups(h.undo[last], entry) {
+		h.undo[last].after = version
+		h.undo[last].entries = append(h.undo[last].entries, entry)
+		h.undo[last].bytes += changed * h.opts.ElementSize
+	} else {
+		h.undo = append(h.undo, step[D]{h.current, version, []Entry{entry}, changed * h.opts.ElementSize})
+	}
+	h.bytes += changed * h.opts.ElementSize
+	h.current, h.grouping = version, true
+	h.trim()
+}
+
+func (h *History[D]) groups(s step[D], entry Entry) bool {
+	last := s.entries[len(s.entries)-1]
+	return h.opts.GroupWithin > 0 && last.Label == entry.Label && entry.Time.Sub(last.Time) <= h.opts.GroupWithin
+}
+
+// Break ends the current group so that the next edit is undone on
+// its own.
+func (h *History[D]) Break() {
+	h.grouping = false
+}
+
+// Undo reverts the last step, returning false if there is nothing to
+// undo.
+func (h *History[D]) Undo() bool {
+	if len(h.undo) == 0 {
+		return false
+	}
+	s := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, s)
+	h.current, h.grouping = s.before, false
+	return true
+}
+
+// Redo reapplies the last undone step, returning false if there is
+// nothing to redo.
+func (h *History[D]) Redo() bool {
+	if len(h.redo) == 0 {
+		return false
+	}
+	s := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, s)
+	h.current, h.grouping = s.after, false
+	return true
+}
+
+// UndoEntries lists the edits of each undo step, oldest first
+func (h *History[D]) UndoEntries() [][]Entry {
+	return entries(h.undo)
+}
+
+// RedoEntries lists the edits of each redo step, the next one to be
+// redone last
+func (h *History[D]) RedoEntries() [][]Entry {
+	return entries(h.redo)
+}
+
+func entries[D any](steps []step[D]) [][]Entry {
+	result := make([][]Entry, len(steps))
+	for kk, s := range steps {
+		result[kk] = append([]Entry(nil), s.entries...)
+	}
+	return result
+}
+
+// trim forgets the oldest undo steps until the limits are met
+func (h *History[D]) trim() {
+	for len(h.undo) > 0 {
+		tooMany := h.opts.MaxSteps > 0 && len(h.undo) > h.opts.MaxSteps
+		tooBig := h.opts.MaxBytes > 0 && h.bytes > h.opts.MaxBytes
+		if !tooMany && !tooBig {
+			return
+		}
+		h.drop(h.undo[:1])
+		h.undo = append(h.undo[:0:0], h.undo[1:]...)
+	}
+}
+
+func (h *History[D]) drop(steps []step[D]) {
+	for _, s := range steps {
+		h.bytes -= s.bytes
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Unix(0, 0)
+	tick := func(d time.Duration) { now = now.Add(d) }
+	h := generic.NewHistory(generic.New(text(""), 0), generic.HistoryOptions{
+		GroupWithin: time.Second,
+		Now:         func() time.Time { return now },
+	})
+	f := h.Current().Forest()
+	typing := func(s string) {
+		for _, r := range s {
+			h.Splice(h.Current().Count, 0, f.New(text(string(r)), 1), "typing")
+			tick(100 * time.Millisecond)
+		}
+	}
+
+	typing("hello")
+	tick(time.Minute)
+	typing(" world")
+	h.Splice(0, 1, f.New("H", 1), "capitalize")
+	if x := toString(h.Current()); x != "Hello world" {
+		t.Fatal("Unexpected", x)
+	}
+	if e := h.UndoEntries(); len(e) != 3 || len(e[0]) != 5 || len(e[1]) != 6 || e[2][0].Label != "capitalize" {
+		t.Fatal("Unexpected entries", e)
+	}
+
+	for _, expected := range []string{"hello world", "hello", ""} {
+		if !h.Undo() || toString(h.Current()) != expected {
+			t.Fatal("Undo", toString(h.Current()), expected)
+		}
+	}
+	if h.Undo() {
+		t.Fatal("Undo past the start")
+	}
+
+	h.Redo()
+	h.Redo()
+	if x := toString(h.Current()); x != "hello world" || len(h.RedoEntries()) != 1 {
+		t.Fatal("Redo", x)
+	}
+
+	h.Break()
+	typing("!")
+	if h.Redo() || len(h.RedoEntries()) != 0 {
+		t.Fatal("Redo after edit")
+	}
+	if h.Undo(); toString(h.Current()) != "hello world" {
+		t.Fatal("Undo after break", toString(h.Current()))
+	}
+}
+
+func TestHistoryLimits(t *testing.T) {
+	h := generic.NewHistory(generic.New(text(""), 0), generic.HistoryOptions{MaxSteps: 3})
+	for kk := 0; kk < 10; kk++ {
+		h.Splice(0, 0, generic.New(text("x"), 1), "")
+	}
+	if len(h.UndoEntries()) != 3 {
+		t.Fatal("Unexpected steps", len(h.UndoEntries()))
+	}
+
+	h = generic.NewHistory(generic.New(text(""), 0), generic.HistoryOptions{MaxBytes: 100, ElementSize: 2})
+	for kk := 0; kk < 10; kk++ {
+		h.Splice(0, 0, generic.New(text("0123456789"), 10), "")
+	}
+	if len(h.UndoEntries()) != 5 {
+		t.Fatal("Unexpected steps", len(h.UndoEntries()))
+	}
+}
+
+func TestHybridHistory(t *testing.T) {
+	h := generic.NewHistory(hybridRaw("hello"), generic.HistoryOptions{})
+	h.Splice(5, 0, hybridRaw(" world"), "append")
+	h.Set(h.Current().Delete(0, 6), "delete")
+	if toStringH(h.Current()) != "world" || !h.Undo() || toStringH(h.Current()) != "hello world" {
+		t.Fatal("Unexpected", toStringH(h.Current()))
+	}
+	if !h.Undo() || toStringH(h.Current()) != "hello" {
+		t.Fatal("Unexpected", toStringH(h.Current()))
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+// Splicer is the interface that the raw elements should implement for
+// use with Hybrid
+type Splicer[T any] interface {
+	Slicer[T]
+	Splice(offset, count int, replacement T) T
+}
+
+// Hybrid switches between a raw array and the Node structure based on
+// size thresholds. The HighMark specifies the size at which a raw
+// string is converted to a Node.  The LowMark controls when a Node is
+// converted back to normal usage. The actual thresholds depend on the
+// underlying structures and are best found via benchmarks.
+//
+// The Count field is only valid if the storage is in Raw rather than
+// in Node.
+//
+// The values returned by Raw.Slice and Raw.Splice are expected to
+// implement Splicer[T] as well.
+//
+// Initialize a new hybrid structure like so:
+//
+//	h := Hybrid[T]{
+//	    HighMark: 15000,
+//	    LowMark: 10000,
+//	    Raw: somethingThatImplementsSlicerAndSplicer,
+//	    Count: sizeOfTheAbove,
+//	}
+type Hybrid[T any] struct {
+	HighMark, LowMark int
+	Raw               Splicer[T]
+	Count             int
+	Node[T]
+}
+
+// Size returns the size irrespective of whether it is stored raw or
+// in a tree
+func (h Hybrid[T]) Size() int {
+	if h.Node.Count == 0 {
+		return h.Count
+	}
+	return h.Node.Count
+}
+
+// ForEach works like Node.ForEach, iteratiing through all the leaf
+// nodes.
+func (h Hybrid[T]) ForEach(fn func(v T, count int)) {
+	if h.Node.Count == 0 {
+		fn(h.raw(), h.Count)
+	} else {
+		h.Node.ForEach(fn)
+	}
+}
+
+// At works like Node.At in both Raw and Node modes.  In Raw mode,
+// the leaf is the Raw value itself.
+func (h Hybrid[T]) At(index int) (leaf T, offset int) {
+	if h.Node.Count > 0 {
+		return h.Node.At(index)
+	}
+	h.checkIndex(index)
+	return h.raw(), index
+}
+
+// Locate works like Node.Locate in both Raw and Node modes.  In Raw
+// mode, the leaf is a new Node (with its own ID space) wrapping the
+// Raw value and the path is empty.
+func (h Hybrid[T]) Locate(index int) (leaf Node[T], offset int, path []int) {
+	if h.Node.Count > 0 {
+		return h.Node.Locate(index)
+	}
+	h.checkIndex(index)
+	return New(h.raw(), h.Count), index, nil
+}
+
+func (h Hybrid[T]) checkIndex(index int) {
+	if index < 0 || index >= h.Count {
+		panic("Unexpected index")
+	}
+}
+
+// Slice returns a sub hybrid with the specified offset and count
+func (h Hybrid[T]) Slice(offset, count int) Hybrid[T] {
+	if checkRange(offset, count, h.Size()) != nil {
+		panic("Unexpected offset, count")
+	}
+
+	if h.Node.Count > 0 {
+		h.Node = h.Node.Slice(offset, count)
+		return h
+	}
+	h.Raw = splicer(h.Raw.Slice(offset, count))
+	h.Count = count
+	return h
+}
+
+// Splice removes the specified offset/count and then replaces it with
+// the provided replacement.  This will convert from Raw to Node and
+// back as specified by the HighMark and LowMark respectively.
+func (h Hybrid[T]) Splice(offset, count int, replacement Hybrid[T]) Hybrid[T] {
+	if checkRange(offset, count, h.Size()) != nil {
+		panic("Unexpected offset, count")
+	}
+
+	if h.Node.Count == 0 && h.Size()+replacement.Size()-count > h.HighMark {
+		h = Hybrid[T]{h.HighMark, h.LowMark, splicer(h.Raw.Slice(0, 0)), 0, New(h.raw(), h.Count)}
+	}
+
+	if h.Node.Count > 0 {
+		n := replacement.Node
+		if n.Count == 0 {
+			n = h.Node.leaf(replacement.raw(), replacement.Count)
+		}
+		h.Node = h.Node.Splice(offset, count, n)
+		if h.Node.Count < h.LowMark {
+			return h.simplify()
+		}
+		return h
+	}
+
+	r := replacement.simplify()
+	h.Raw = splicer(h.Raw.Splice(offset, count, r.raw()))
+	h.Count += r.Count - count
+	if h.Count > h.HighMark {
+		return Hybrid[T]{h.HighMark, h.LowMark, splicer(h.Raw.Slice(0, 0)), 0, New(h.raw(), h.Count)}
+	}
+
+	return h
+}
+
+func (h Hybrid[T]) simplify() Hybrid[T] {
+	if h.Node.Count == 0 {
+		return h
+	}
+
+	raw := splicer(h.Raw.Slice(0, 0))
+	total := 0
+	h.Node.ForEach(func(v T, count int) {
+		raw = splicer(raw.Splice(total, 0, v))
+		total += count
+	})
+	return Hybrid[T]{h.HighMark, h.LowMark, raw, total, Node[T]{}}
+}
+
+// raw returns the Raw value as a leaf value
+func (h Hybrid[T]) raw() T {
+	v, _ := any(h.Raw).(T)
+	return v
+}
+
+// splicer converts the result of a Slice or Splice back to a Splicer
+func splicer[T any](v T) Splicer[T] {
+	return any(v).(Splicer[T])
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"testing"
+)
+
+func hybridRaw(str string) generic.Hybrid[text] {
+	return generic.Hybrid[text]{HighMark: 10, LowMark: 5, Raw: text(str), Count: len(str)}
+}
+
+func TestHybridSwitcheroo(t *testing.T) {
+	hw := hybridRaw("").Splice(0, 0, hybridRaw("hello world"))
+	if hw.Count != 0 || hw.Node.Count != 11 {
+		t.Fatal("Failed to switch to larger size", hw.Count)
+	}
+
+	spliced := hw.Splice(0, 0, hybridRaw("ok "))
+	if x := toStringH(spliced); x != "ok hello world" {
+		t.Fatal("Failed to splice and keep nodes", x)
+	}
+
+	if x := toStringH(spliced.Slice(3, 11)); x != "hello world" {
+		t.Fatal("Slice on Node failed", x)
+	}
+
+	small := hw.Splice(0, 7, hybridRaw(""))
+	if small.Count != 4 || small.Raw.(text) != "orld" {
+		t.Fatal("Failed to switch back to smaller size", small.Raw)
+	}
+}
+
+func TestHybridAt(t *testing.T) {
+	raw := hybridRaw("hello")
+	if leaf, offset := raw.At(1); leaf != "hello" || offset != 1 {
+		t.Fatal("At on Raw", leaf, offset)
+	}
+	if leaf, offset, path := raw.Locate(4); leaf.Leaf != "hello" || offset != 4 || path != nil {
+		t.Fatal("Locate on Raw", leaf, offset, path)
+	}
+	if leaf, _, _ := raw.Locate(0); toString(leaf.Splice(5, 0, leaf.Slice(0, 1))) != "helloh" {
+		t.Fatal("Edit of located Raw leaf", toString(leaf))
+	}
+
+	node := raw.Splice(5, 0, hybridRaw(" world"))
+	if leaf, offset := node.At(7); leaf[offset] != 'o' {
+		t.Fatal("At on Node", leaf, offset)
+	}
+	if leaf, offset, _ := node.Locate(10); leaf.Leaf[offset] != 'd' {
+		t.Fatal("Locate on Node", leaf, offset)
+	}
+
+	mustPanic(t, func() { raw.At(5) })
+}
+
+func toStringH(h generic.Hybrid[text]) string {
+	result := ""
+	h.ForEach(func(leaf text, count int) {
+		result += string(leaf)
+	})
+	return result
+}
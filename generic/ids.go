@@ -0,0 +1,183 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IDSource hands out the IDs of nodes.  All the nodes derived from
+// nodes of the same source have unique IDs.  It is safe to derive
+// nodes from the same source on multiple goroutines.
+//
+// The zero value uses a counter starting at 1.  Use NewIDSource to
+// pick a different IDGenerator.
+type IDSource struct {
+	last atomic.Int64
+	gen  IDGenerator
+}
+
+// NewIDSource creates an ID source which uses the provided
+// generator.  A nil generator is the same as the zero IDSource.
+func NewIDSource(gen IDGenerator) *IDSource {
+	return &IDSource{gen: gen}
+}
+
+func (s *IDSource) next() int {
+	if s.gen != nil {
+		return s.gen.Next()
+	}
+	return int(s.last.Add(1))
+}
+
+// UID returns the 128-bit form of an ID handed out by the source.
+func (s *IDSource) UID(id int) UID {
+	if s == nil || s.gen == nil {
+		return plainUID(id)
+	}
+	return s.gen.UID(id)
+}
+
+// plainUID is the UID of an ID handed out by a Counter (or the zero
+// IDSource)
+func plainUID(id int) UID {
+	var uid UID
+	binary.BigEndian.PutUint64(uid[8:], uint64(id))
+	return uid
+}
+
+// restore records the UID of an ID read by a decoder.  If the UID is
+// not plain, the source switches to a generator which remembers the
+// UIDs of the restored IDs and uses a TimeOrderedIDs generator for
+// new IDs.  Restore must be called before the nodes are used.
+func (s *IDSource) restore(id int, uid UID) {
+	if uid == plainUID(id) {
+		s.reserve(id)
+		return
+	}
+
+	d, ok := s.gen.(*decodedIDs)
+	if !ok {
+		d = &decodedIDs{uids: map[int]UID{}}
+		d.prefix = timePrefix()
+		d.last.Store(s.last.Load())
+		s.gen = d
+	}
+	d.mu.Lock()
+	d.uids[id] = uid
+	d.mu.Unlock()
+	s.reserve(id)
+}
+
+// reserve makes sure that new IDs are handed out after id
+func (s *IDSource) reserve(id int) {
+	last := &s.last
+	if d, ok := s.gen.(*decodedIDs); ok {
+		last = &d.last
+	}
+	if int64(id) > last.Load() {
+		last.Store(int64(id))
+	}
+}
+
+// UID returns the 128-bit form of the ID of the node.  Unlike the
+// ID, this is unique across ID sources (and processes) if the source
+// uses RandomIDs or TimeOrderedIDs.
+func (n Node[T]) UID() UID {
+	return n.ids.UID(n.ID)
+}
+
+// UID is a 128-bit node ID.
+type UID [16]byte
+
+func (u UID) String() string {
+	return hex.EncodeToString(u[:])
+}
+
+// IDGenerator generates node IDs for an IDSource.  Next must be safe
+// to call from multiple goroutines and must not return the same ID
+// twice.  UID maps an ID returned by Next to its 128-bit form.
+type IDGenerator interface {
+	Next() int
+	UID(id int) UID
+}
+
+// Counter returns a generator that counts up from start+1.  The IDs
+// are deterministic, which is useful for reproducible tests, and the
+// UIDs are simply the IDs.
+func Counter(start int) IDGenerator {
+	c := &counter{}
+	c.last.Store(int64(start))
+	return c
+}
+
+// RandomIDs returns a generator whose UIDs start with 64 random bits
+// followed by the ID.  The UIDs are unique across generators.
+func RandomIDs() IDGenerator {
+	c := &counter{}
+	c.prefix = binary.BigEndian.Uint64(random(8))
+	return c
+}
+
+// TimeOrderedIDs is like RandomIDs but the UIDs start with the
+// creation time of the generator in milliseconds (48 bits) followed
+// by 16 random bits.  UIDs from generators created later sort after.
+func TimeOrderedIDs() IDGenerator {
+	c := &counter{}
+	c.prefix = timePrefix()
+	return c
+}
+
+func timePrefix() uint64 {
+	ms := uint64(time.Now().UnixMilli())
+	return ms<<16 | uint64(binary.BigEndian.Uint16(random(2)))
+}
+
+type counter struct {
+	last   atomic.Int64
+	prefix uint64
+}
+
+func (c *counter) Next() int {
+	return int(c.last.Add(1))
+}
+
+func (c *counter) UID(id int) UID {
+	var uid UID
+	binary.BigEndian.PutUint64(uid[:8], c.prefix)
+	binary.BigEndian.PutUint64(uid[8:], uint64(id))
+	return uid
+}
+
+// decodedIDs is the generator of decoded ID spaces whose UIDs are
+// not plain
+type decodedIDs struct {
+	counter
+	mu   sync.RWMutex
+	uids map[int]UID
+}
+
+func (d *decodedIDs) UID(id int) UID {
+	d.mu.RLock()
+	uid, ok := d.uids[id]
+	d.mu.RUnlock()
+	if ok {
+		return uid
+	}
+	return d.counter.UID(id)
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/perdata/trope/generic"
+	"testing"
+	"time"
+)
+
+func TestCounterIDs(t *testing.T) {
+	build := func() []int {
+		f := generic.NewForestWithIDs[text](generic.NewIDSource(generic.Counter(100)))
+		n := f.New("hello", 5).Append(" world", 6).Splice(5, 1, f.New(",", 1))
+		ids := []int{n.ID}
+		for _, child := range n.Children {
+			ids = append(ids, child.ID)
+		}
+		return ids
+	}
+
+	a, b := build(), build()
+	for kk := range a {
+		if a[kk] != b[kk] || a[kk] <= 100 {
+			t.Fatal("Unexpected IDs", a, b)
+		}
+	}
+}
+
+func TestUIDs(t *testing.T) {
+	n := generic.New(text("hello"), 5).Append("!", 1)
+	if uid := n.UID(); uid.String() != fmt.Sprintf("%032x", n.ID) {
+		t.Fatal("Unexpected UID", uid)
+	}
+
+	x := generic.NewForestWithIDs[text](generic.NewIDSource(generic.RandomIDs())).New("x", 1)
+	y := generic.NewForestWithIDs[text](generic.NewIDSource(generic.RandomIDs())).New("y", 1)
+	if x.ID != y.ID || x.UID() == y.UID() {
+		t.Fatal("Unexpected random UIDs", x.UID(), y.UID())
+	}
+
+	early := generic.NewIDSource(generic.TimeOrderedIDs())
+	time.Sleep(2 * time.Millisecond)
+	late := generic.NewIDSource(generic.TimeOrderedIDs())
+	a, b := early.UID(1000), late.UID(1)
+	if bytes.Compare(a[:], b[:]) >= 0 {
+		t.Fatal("Unexpected order", a, b)
+	}
+}
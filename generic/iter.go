@@ -0,0 +1,199 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "iter"
+
+// Indexer is an optional interface to be implemented by the leaf-node
+// values to provide access to individual elements.  Leaves with a
+// count of one need not implement this if the leaf value is itself
+// the element.
+type Indexer[E any] interface {
+	Index(offset int) E
+}
+
+// Iterable is implemented by both Node and Hybrid.
+type Iterable[T any] interface {
+	LeavesFrom(offset int) iter.Seq2[T, int]
+	ReverseLeavesFrom(offset int) iter.Seq2[T, int]
+}
+
+// Leaves returns an iterator over all the leaf values and their
+// counts.  It is the range-over-func equivalent of ForEach and
+// likewise panics if a leaf loaded by a NodeStore cannot be read.
+func (n Node[T]) Leaves() iter.Seq2[T, int] {
+	return n.LeavesFrom(0)
+}
+
+// LeavesFrom returns an iterator over the leaf values starting at
+// the provided offset.  The first leaf is sliced (via Slicer) if the
+// offset falls within it.  Seeking to the offset takes time
+// proportional to the depth of the tree.  It panics if a leaf loaded
+// by a NodeStore cannot be read.
+func (n Node[T]) LeavesFrom(offset int) iter.Seq2[T, int] {
+	if offset < 0 || offset > n.Count {
+		panic("Unexpected offset")
+	}
+	return func(yield func(T, int) bool) {
+		n.leavesFrom(offset, yield)
+	}
+}
+
+func (n Node[T]) leavesFrom(offset int, yield func(T, int) bool) bool {
+	if offset == n.Count {
+		return true
+	}
+
+	if n.Children == nil {
+		if offset > 0 {
+			return yield(n.leafSlice(offset, n.Count-offset), n.Count-offset)
+		}
+		return yield(n.value(), n.Count)
+	}
+
+	kk, offset := n.child(offset)
+	for ; kk < len(n.Children); kk++ {
+		if !n.Children[kk].leavesFrom(offset, yield) {
+			return false
+		}
+		offset = 0
+	}
+	return true
+}
+
+// ReverseLeaves returns an iterator over all the leaf values and
+// their counts, starting from the last leaf.  Like Leaves, it panics
+// if a leaf loaded by a NodeStore cannot be read.
+func (n Node[T]) ReverseLeaves() iter.Seq2[T, int] {
+	return n.ReverseLeavesFrom(n.Count)
+}
+
+// ReverseLeavesFrom returns an iterator over the leaf values before
+// the provided offset, in reverse order.  The first leaf yielded is
+// sliced (via Slicer) to end at the offset if needed.  Seeking to the
+// offset takes time proportional to the depth of the tree.  Leaves
+// loaded by a NodeStore which cannot be read cause a panic.
+func (n Node[T]) ReverseLeavesFrom(offset int) iter.Seq2[T, int] {
+	if offset < 0 || offset > n.Count {
+		panic("Unexpected offset")
+	}
+	return func(yield func(T, int) bool) {
+		n.reverseLeavesFrom(offset, yield)
+	}
+}
+
+func (n Node[T]) reverseLeavesFrom(offset int, yield func(T, int) bool) bool {
+	if offset == 0 {
+		return true
+	}
+
+	if n.Children == nil {
+		if offset < n.Count {
+			return yield(n.leafSlice(0, offset), offset)
+		}
+		return yield(n.value(), n.Count)
+	}
+
+	kk, offset := n.child(offset - 1)
+	for offset++; kk >= 0; kk-- {
+		if !n.Children[kk].reverseLeavesFrom(offset, yield) {
+			return false
+		}
+		if kk > 0 {
+			offset = n.Children[kk-1].Count
+		}
+	}
+	return true
+}
+
+// Leaves works like Node.Leaves in both Raw and Node modes.
+func (h Hybrid[T]) Leaves() iter.Seq2[T, int] {
+	return h.LeavesFrom(0)
+}
+
+// LeavesFrom works like Node.LeavesFrom in both Raw and Node modes.
+func (h Hybrid[T]) LeavesFrom(offset int) iter.Seq2[T, int] {
+	if h.Node.Count > 0 {
+		return h.Node.LeavesFrom(offset)
+	}
+	if offset < 0 || offset > h.Count {
+		panic("Unexpected offset")
+	}
+	return func(yield func(T, int) bool) {
+		switch {
+		case offset == h.Count:
+		case offset > 0:
+			yield(h.Raw.Slice(offset, h.Count-offset), h.Count-offset)
+		default:
+			yield(h.raw(), h.Count)
+		}
+	}
+}
+
+// ReverseLeaves works like Node.ReverseLeaves in both Raw and Node
+// modes.
+func (h Hybrid[T]) ReverseLeaves() iter.Seq2[T, int] {
+	return h.ReverseLeavesFrom(h.Size())
+}
+
+// ReverseLeavesFrom works like Node.ReverseLeavesFrom in both Raw and
+// Node modes.
+func (h Hybrid[T]) ReverseLeavesFrom(offset int) iter.Seq2[T, int] {
+	if h.Node.Count > 0 {
+		return h.Node.ReverseLeavesFrom(offset)
+	}
+	if offset < 0 || offset > h.Count {
+		panic("Unexpected offset")
+	}
+	return func(yield func(T, int) bool) {
+		switch {
+		case offset == 0:
+		case offset < h.Count:
+			yield(h.Raw.Slice(0, offset), offset)
+		default:
+			yield(h.raw(), h.Count)
+		}
+	}
+}
+
+// Elements returns an iterator over the individual elements of a
+// Node or Hybrid starting at the provided offset.  Each element is
+// yielded with its index.  The leaf values must implement Indexer[E]
+// unless they are single-element leaves of type E.
+func Elements[E, T any](it Iterable[T], offset int) iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		for leaf, count := range it.LeavesFrom(offset) {
+			for kk := 0; kk < count; kk++ {
+				if !yield(offset, element[E](leaf, kk, count)) {
+					return
+				}
+				offset++
+			}
+		}
+	}
+}
+
+func element[E, T any](leaf T, offset, count int) E {
+	if e, ok := any(leaf).(E); ok && count == 1 {
+		return e
+	}
+	return any(leaf).(Indexer[E]).Index(offset)
+}
+
+// ReverseElements returns an iterator over the individual elements
+// of a Node or Hybrid before the provided offset, in reverse order.
+// Each element is yielded with its index.
+func ReverseElements[E, T any](it Iterable[T], offset int) iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		for leaf, count := range it.ReverseLeavesFrom(offset) {
+			for kk := count - 1; kk >= 0; kk-- {
+				offset--
+				if !yield(offset, element[E](leaf, kk, count)) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"testing"
+)
+
+func TestLeavesFrom(t *testing.T) {
+	n := generic.New(text("hello"), 5).
+		Splice(5, 0, generic.New(text(" world"), 6)).
+		Splice(0, 0, generic.New(text(">"), 1))
+	str := ">hello world"
+
+	for offset := 0; offset <= len(str); offset++ {
+		result := ""
+		for leaf, count := range n.LeavesFrom(offset) {
+			if len(leaf) != count {
+				t.Fatal("count mismatch", leaf, count)
+			}
+			result += string(leaf)
+		}
+		if result != str[offset:] {
+			t.Fatal("LeavesFrom", offset, result)
+		}
+	}
+
+	for leaf := range n.Leaves() {
+		if leaf != ">" {
+			t.Fatal("break", leaf)
+		}
+		break
+	}
+
+	mustPanic(t, func() { n.LeavesFrom(len(str) + 1) })
+}
+
+func TestElements(t *testing.T) {
+	str := "hello world"
+	h := hybridRaw("hello")
+	for _, n := range []generic.Hybrid[text]{h, h.Splice(5, 0, hybridRaw(" world"))} {
+		s := str[:n.Size()]
+		for offset := 0; offset <= len(s); offset++ {
+			result := ""
+			for idx, b := range generic.Elements[byte](n, offset) {
+				if s[idx] != b {
+					t.Fatal("index mismatch", idx, b)
+				}
+				result += string(b)
+			}
+			if result != s[offset:] {
+				t.Fatal("Elements", offset, result)
+			}
+		}
+	}
+
+	single := generic.New(42, 1).Splice(1, 0, generic.New(43, 1))
+	sum := 0
+	for _, v := range generic.Elements[int](single, 0) {
+		sum += v
+		break
+	}
+	if sum != 42 {
+		t.Fatal("single element leaves", sum)
+	}
+}
+
+func TestReverseLeavesFrom(t *testing.T) {
+	n := generic.New(text("hello"), 5).
+		Splice(5, 0, generic.New(text(" world"), 6)).
+		Splice(0, 0, generic.New(text(">"), 1)).
+		Flatten(2)
+	str := ">hello world"
+
+	for offset := 0; offset <= len(str); offset++ {
+		result := ""
+		for leaf, count := range n.ReverseLeavesFrom(offset) {
+			if len(leaf) != count {
+				t.Fatal("count mismatch", leaf, count)
+			}
+			result = string(leaf) + result
+		}
+		if result != str[:offset] {
+			t.Fatal("ReverseLeavesFrom", offset, result)
+		}
+	}
+
+	for leaf := range n.ReverseLeaves() {
+		if leaf != " world" {
+			t.Fatal("break", leaf)
+		}
+		break
+	}
+
+	mustPanic(t, func() { n.ReverseLeavesFrom(-1) })
+}
+
+func TestReverseElements(t *testing.T) {
+	str := "hello world"
+	h := hybridRaw("hello")
+	for _, n := range []generic.Hybrid[text]{h, h.Splice(5, 0, hybridRaw(" world"))} {
+		s := str[:n.Size()]
+		for offset := 0; offset <= len(s); offset++ {
+			result := ""
+			for idx, b := range generic.ReverseElements[byte](n, offset) {
+				if s[idx] != b {
+					t.Fatal("index mismatch", idx, b)
+				}
+				result = string(b) + result
+			}
+			if result != s[:offset] {
+				t.Fatal("ReverseElements", offset, result)
+			}
+		}
+	}
+
+	for idx := range generic.ReverseElements[byte](h, 5) {
+		if idx != 4 {
+			t.Fatal("break", idx)
+		}
+		break
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// JSONOptions controls the JSON encoding of nodes.  By default, the
+// content is encoded as a flat JSON string (if the leaves are
+// strings) or array.  Nested encodes the tree itself, with the ID,
+// Count and Children (or Leaf) of every node.
+//
+// The plain Go values the flat encoding decodes to do not implement
+// Slicer, so decoding it with an interface leaf type T (such as with
+// trope.Node) needs FlatLeaf: a leaf value of the string (or slice)
+// type implementing Slicer[T] to decode the content as.  Decoding
+// ignores Nested.
+type JSONOptions struct {
+	Nested   bool
+	FlatLeaf any
+}
+
+var leafTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{byName: map[string]reflect.Type{}, byType: map[reflect.Type]string{}}
+
+// RegisterLeafType registers the type of the provided leaf value
+// under the name.  Nested JSON encoding records the name of the type
+// of registered leaves when the leaf type T is an interface type
+// (such as with trope.Node) so that decoding restores the leaves to
+// the same type.  Much like gob.Register, it panics if the name or
+// the type is already registered differently.
+func RegisterLeafType(name string, leaf any) {
+	leafTypes.Lock()
+	defer leafTypes.Unlock()
+	typ := reflect.TypeOf(leaf)
+	if t, ok := leafTypes.byName[name]; ok && t != typ {
+		panic(fmt.Sprintf("trope: leaf type name %q registered for both %v and %v", name, t, typ))
+	}
+	if n, ok := leafTypes.byType[typ]; ok && n != name {
+		panic(fmt.Sprintf("trope: leaf type %v registered as both %q and %q", typ, n, name))
+	}
+	leafTypes.byName[name] = typ
+	leafTypes.byType[typ] = name
+}
+
+// MarshalJSON implements json.Marshaler using the flat encoding
+func (n Node[T]) MarshalJSON() ([]byte, error) {
+	return n.MarshalJSONWith(JSONOptions{})
+}
+
+// MarshalJSONWith is like MarshalJSON with the provided options
+func (n Node[T]) MarshalJSONWith(opts JSONOptions) ([]byte, error) {
+	if opts.Nested {
+		nested, err := n.nestedJSON()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(nested)
+	}
+	return n.flatJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  Both the flat and the
+// nested encodings are accepted.  Nodes decoded from the nested
+// encoding keep their IDs (and UIDs).
+func (n *Node[T]) UnmarshalJSON(data []byte) error {
+	return n.UnmarshalJSONWith(data, JSONOptions{})
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON with the provided options
+func (n *Node[T]) UnmarshalJSONWith(data []byte, opts JSONOptions) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var nested jsonNode
+		if err := json.Unmarshal(data, &nested); err != nil {
+			return err
+		}
+		ids := &IDSource{}
+		result, err := nodeFromJSON[T](&nested, ids, map[int]Node[T]{})
+		if err == nil {
+			*n = result
+		}
+		return err
+	}
+
+	result, err := flatFromJSON[T](data, opts.FlatLeaf)
+	if err == nil {
+		*n = result
+	}
+	return err
+}
+
+func (n Node[T]) flatJSON() ([]byte, error) {
+	var str strings.Builder
+	elems, strs := []any{}, reflect.TypeFor[T]().Kind() == reflect.String
+	var err error
+	n.ForEach(func(v T, count int) {
+		rv := reflect.ValueOf(any(v))
+		switch {
+		case !rv.IsValid():
+			err = fmt.Errorf("trope: cannot encode nil leaf")
+		case rv.Kind() == reflect.String:
+			str.WriteString(rv.String())
+			strs = true
+		case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+			for kk := 0; kk < rv.Len(); kk++ {
+				elems = append(elems, rv.Index(kk).Interface())
+			}
+		case count == 1:
+			elems = append(elems, v)
+		default:
+			err = fmt.Errorf("trope: cannot encode leaf of type %T with count %d", v, count)
+		}
+	})
+
+	switch {
+	case err != nil:
+		return nil, err
+	case strs && len(elems) > 0:
+		return nil, fmt.Errorf("trope: cannot encode a mix of string and other leaves")
+	case strs:
+		return json.Marshal(str.String())
+	}
+	return json.Marshal(elems)
+}
+
+func flatFromJSON[T any](data []byte, flatLeaf any) (Node[T], error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() == reflect.Interface {
+		return flatFromJSONAs[T](data, flatLeaf)
+	}
+	if typ.Kind() == reflect.String || typ.Kind() == reflect.Slice {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return Node[T]{}, err
+		}
+		return New(v, reflect.ValueOf(any(v)).Len()), nil
+	}
+
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return Node[T]{}, err
+	}
+	var zero T
+	n := New(zero, 0)
+	for _, elem := range elems {
+		var v T
+		if err := json.Unmarshal(elem, &v); err != nil {
+			return Node[T]{}, err
+		}
+		n = n.Append(v, 1)
+	}
+	return n, nil
+}
+
+// flatFromJSONAs decodes the flat encoding for an interface type T
+// as the type of flatLeaf
+func flatFromJSONAs[T any](data []byte, flatLeaf any) (Node[T], error) {
+	typ := reflect.TypeOf(flatLeaf)
+	switch {
+	case typ == nil:
+		return Node[T]{}, fmt.Errorf("trope: decoding flat JSON as %v needs JSONOptions.FlatLeaf", reflect.TypeFor[T]())
+	case typ.Kind() != reflect.String && typ.Kind() != reflect.Slice || !typ.Implements(reflect.TypeFor[Slicer[T]]()):
+		return Node[T]{}, fmt.Errorf("trope: flat leaf type %v is not a string or slice implementing Slicer[%v]", typ, reflect.TypeFor[T]())
+	}
+
+	v := reflect.New(typ)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return Node[T]{}, err
+	}
+	return New(v.Elem().Interface().(T), v.Elem().Len()), nil
+}
+
+type jsonNode struct {
+	ID       int
+	UID      string `json:",omitempty"`
+	Count    int
+	Type     string          `json:",omitempty"`
+	Leaf     json.RawMessage `json:",omitempty"`
+	Children []*jsonNode     `json:",omitempty"`
+}
+
+func (n Node[T]) nestedJSON() (*jsonNode, error) {
+	result := &jsonNode{ID: n.ID, Count: n.Count}
+	if uid := n.UID(); uid != plainUID(n.ID) {
+		result.UID = uid.String()
+	}
+	if n.Children != nil {
+		result.Children = []*jsonNode{}
+		for _, child := range n.Children {
+			c, err := child.nestedJSON()
+			if err != nil {
+				return nil, err
+			}
+			result.Children = append(result.Children, c)
+		}
+		return result, nil
+	}
+
+	n, err := n.Resolve()
+	if err == nil {
+		result.Type, result.Leaf, err = leafJSON(n.Leaf)
+	}
+	return result, err
+}
+
+// leafJSON encodes the leaf along with the name of its type if T is
+// an interface type and the type of the leaf is registered
+func leafJSON[T any](v T) (string, json.RawMessage, error) {
+	name := ""
+	if reflect.TypeFor[T]().Kind() == reflect.Interface {
+		leafTypes.RLock()
+		name = leafTypes.byType[reflect.TypeOf(any(v))]
+		leafTypes.RUnlock()
+	}
+	data, err := json.Marshal(v)
+	return name, data, err
+}
+
+func leafFromJSON[T any](name string, data json.RawMessage) (T, error) {
+	var leaf T
+	if name == "" {
+		err := json.Unmarshal(data, &leaf)
+		return leaf, err
+	}
+
+	leafTypes.RLock()
+	typ, ok := leafTypes.byName[name]
+	leafTypes.RUnlock()
+	if !ok {
+		return leaf, fmt.Errorf("trope: unknown leaf type %q", name)
+	}
+	v := reflect.New(typ)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return leaf, err
+	}
+	leaf, ok = v.Elem().Interface().(T)
+	if !ok {
+		return leaf, fmt.Errorf("trope: leaf type %q is not a %v", name, reflect.TypeFor[T]())
+	}
+	return leaf, nil
+}
+
+func nodeFromJSON[T any](j *jsonNode, ids *IDSource, seen map[int]Node[T]) (Node[T], error) {
+	if n, ok := seen[j.ID]; ok && n.Count == j.Count && (n.Children == nil) == (j.Children == nil) {
+		return n, nil
+	}
+	uid := plainUID(j.ID)
+	if j.UID != "" {
+		b, err := hex.DecodeString(j.UID)
+		if err != nil || len(b) != len(uid) {
+			return Node[T]{}, fmt.Errorf("trope: invalid UID %q", j.UID)
+		}
+		copy(uid[:], b)
+	}
+	if j.Count < 0 {
+		return Node[T]{}, fmt.Errorf("trope: invalid count %d for node %d", j.Count, j.ID)
+	}
+	ids.restore(j.ID, uid)
+
+	n := Node[T]{ID: j.ID, ids: ids, Count: j.Count}.withCache()
+	switch {
+	case j.Children != nil:
+		n.Children = make([]Node[T], len(j.Children))
+		total := 0
+		for kk, child := range j.Children {
+			c, err := nodeFromJSON[T](child, ids, seen)
+			if err != nil {
+				return n, err
+			}
+			n.Children[kk] = c
+			total += c.Count
+		}
+		if total != n.Count {
+			return n, fmt.Errorf("trope: invalid count %d for node %d", j.Count, j.ID)
+		}
+	case j.Leaf != nil:
+		leaf, err := leafFromJSON[T](j.Type, j.Leaf)
+		if err != nil {
+			return n, err
+		}
+		if l, ok := any(leaf).(Lener); ok && l.Len() != n.Count {
+			return n, fmt.Errorf("trope: invalid count %d for node %d", j.Count, j.ID)
+		}
+		n.Leaf = leaf
+	case n.Count > 0:
+		return n, fmt.Errorf("trope: missing leaf for node %d", j.ID)
+	}
+	seen[j.ID] = n
+	return n, nil
+}
+
+type jsonHybrid struct {
+	HighMark, LowMark int
+	Count             int    `json:",omitempty"`
+	RawType           string `json:",omitempty"`
+	Raw               json.RawMessage
+	Node              *jsonNode `json:",omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler using the flat encoding
+func (h Hybrid[T]) MarshalJSON() ([]byte, error) {
+	return h.MarshalJSONWith(JSONOptions{})
+}
+
+// MarshalJSONWith is like MarshalJSON with the provided options.  The
+// nested encoding also holds the marks and, in Raw mode, the Raw
+// value instead of a tree.
+func (h Hybrid[T]) MarshalJSONWith(opts JSONOptions) ([]byte, error) {
+	switch {
+	case !opts.Nested && h.Node.Count > 0:
+		return h.Node.flatJSON()
+	case !opts.Nested:
+		return New(h.raw(), h.Count).flatJSON()
+	}
+
+	j := jsonHybrid{HighMark: h.HighMark, LowMark: h.LowMark}
+	raw := h.Raw.Slice(0, 0)
+	if h.Node.Count == 0 {
+		raw, j.Count = h.raw(), h.Count
+	}
+
+	var err error
+	if j.RawType, j.Raw, err = leafJSON(raw); err == nil && h.Node.Count > 0 {
+		j.Node, err = h.Node.nestedJSON()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  Both the flat and the
+// nested encodings are accepted.  The flat encoding has no marks, so
+// the marks of h are left as is.  The leaves must implement
+// Splicer[T].
+func (h *Hybrid[T]) UnmarshalJSON(data []byte) error {
+	return h.UnmarshalJSONWith(data, JSONOptions{})
+}
+
+// UnmarshalJSONWith is like UnmarshalJSON with the provided options.
+// If FlatLeaf is not set, the flat encoding is decoded as the type of
+// h.Raw (if set).
+func (h *Hybrid[T]) UnmarshalJSONWith(data []byte, opts JSONOptions) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		return h.nestedFromJSON(data)
+	}
+
+	if opts.FlatLeaf == nil && h.Raw != nil {
+		opts.FlatLeaf = h.Raw
+	}
+	var n Node[T]
+	if err := n.UnmarshalJSONWith(data, opts); err != nil {
+		return err
+	}
+	first := n.Leaf
+	if n.Children != nil {
+		first = n.Children[0].Leaf
+	}
+	s, ok := any(first).(Splicer[T])
+	if !ok {
+		return fmt.Errorf("trope: leaf type %T does not implement Splicer", first)
+	}
+
+	empty := Hybrid[T]{h.HighMark, h.LowMark, splicer(s.Slice(0, 0)), 0, Node[T]{}}
+	raw := empty
+	raw.Node = n
+	*h = empty.Splice(0, 0, raw.simplify())
+	return nil
+}
+
+func (h *Hybrid[T]) nestedFromJSON(data []byte) error {
+	var j jsonHybrid
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	raw, err := leafFromJSON[T](j.RawType, j.Raw)
+	if err != nil {
+		return err
+	}
+	s, ok := any(raw).(Splicer[T])
+	if !ok {
+		return fmt.Errorf("trope: leaf type %T does not implement Splicer", raw)
+	}
+
+	result := Hybrid[T]{j.HighMark, j.LowMark, s, j.Count, Node[T]{}}
+	if j.Node != nil {
+		result.Node, err = nodeFromJSON[T](j.Node, &IDSource{}, map[int]Node[T]{})
+	}
+	if err == nil {
+		*h = result
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"encoding/json"
+	"github.com/perdata/trope/generic"
+	"strings"
+	"testing"
+)
+
+type point struct{ X, Y int }
+
+// anyText is a string leaf for nodes of interface{}
+type anyText string
+
+func (s anyText) Slice(offset, count int) interface{} {
+	return s[offset : offset+count]
+}
+
+func TestJSONFlat(t *testing.T) {
+	n := generic.New(text("hello"), 5).Append(" world", 6)
+	data, err := json.Marshal(n)
+	if err != nil || string(data) != `"hello world"` {
+		t.Fatal("Marshal", string(data), err)
+	}
+	var m generic.Node[text]
+	if err := json.Unmarshal(data, &m); err != nil || toString(m) != "hello world" || m.Count != 11 {
+		t.Fatal("Unmarshal", toString(m), err)
+	}
+
+	ints := generic.New([]int{1, 2}, 2).Append([]int{3}, 1)
+	if data, err := json.Marshal(ints); err != nil || string(data) != `[1,2,3]` {
+		t.Fatal("Marshal ints", string(data), err)
+	}
+
+	points := generic.New(point{1, 2}, 1).Append(point{3, 4}, 1)
+	data, err = json.Marshal(points)
+	if err != nil || string(data) != `[{"X":1,"Y":2},{"X":3,"Y":4}]` {
+		t.Fatal("Marshal points", string(data), err)
+	}
+	var p generic.Node[point]
+	if err := json.Unmarshal(data, &p); err != nil || p.Count != 2 {
+		t.Fatal("Unmarshal points", p, err)
+	}
+	if leaf, _ := p.At(1); leaf != (point{3, 4}) {
+		t.Fatal("Unexpected point", leaf)
+	}
+}
+
+func TestJSONNested(t *testing.T) {
+	n := generic.New(text("hello"), 5).Append(" world", 6)
+	n = n.Splice(0, 0, n)
+	data, err := n.MarshalJSONWith(generic.JSONOptions{Nested: true})
+	if err != nil || !strings.Contains(string(data), `"Children":[`) {
+		t.Fatal("Marshal", string(data), err)
+	}
+
+	var m generic.Node[text]
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+	if toString(m) != toString(n) || m.ID != n.ID || m.Stats() != n.Stats() {
+		t.Fatal("Diverged", toString(m), toString(n))
+	}
+	if err := m.Append("!", 1).Validate(); err != nil {
+		t.Fatal("Invalid", err)
+	}
+
+	for _, bad := range []string{
+		`{"ID":1,"Count":10,"Children":[{"ID":2,"Count":3,"Leaf":"abc"}]}`,
+		`{"ID":1,"Count":-4,"Leaf":"abc"}`,
+		`{"ID":1,"Count":4,"Leaf":"abc"}`,
+		`{"ID":1,"Count":3}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &m); err == nil {
+			t.Fatal("Unexpected success", bad)
+		}
+	}
+}
+
+func TestJSONLeafTypes(t *testing.T) {
+	generic.RegisterLeafType("text", text(""))
+	generic.RegisterLeafType("point", point{})
+
+	n := generic.New[interface{}](text("hello"), 5).Append(point{1, 2}, 1)
+	data, err := n.MarshalJSONWith(generic.JSONOptions{Nested: true})
+	if err != nil {
+		t.Fatal("Marshal", err)
+	}
+
+	var m generic.Node[interface{}]
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal("Unmarshal", err)
+	}
+	if leaf, _ := m.At(0); leaf != text("hello") {
+		t.Fatalf("Unexpected leaf %#v", leaf)
+	}
+	if leaf, _ := m.At(5); leaf != (point{1, 2}) {
+		t.Fatalf("Unexpected leaf %#v", leaf)
+	}
+
+	// registering again is fine but not with a different type
+	generic.RegisterLeafType("point", point{})
+	mustPanic(t, func() { generic.RegisterLeafType("point", anyText("")) })
+
+	bad := strings.Replace(string(data), `"point"`, `"unknown"`, 1)
+	if err := json.Unmarshal([]byte(bad), &m); err == nil {
+		t.Fatal("Unexpected success with unknown type")
+	}
+
+	// flat decoding needs the leaf type to decode as
+	for _, flat := range []string{`"hello"`, `[1,2]`} {
+		if err := json.Unmarshal([]byte(flat), &m); err == nil {
+			t.Fatal("Unexpected success without a leaf type", flat)
+		}
+	}
+	if err := m.UnmarshalJSONWith([]byte(`"hello"`), generic.JSONOptions{FlatLeaf: text("")}); err == nil {
+		t.Fatal("Unexpected success with a leaf type that is not a Slicer")
+	}
+	if err := m.UnmarshalJSONWith([]byte(`"hello"`), generic.JSONOptions{FlatLeaf: anyText("")}); err != nil || m.Count != 5 {
+		t.Fatal("Unmarshal flat", err)
+	}
+	if leaf, _ := m.Splice(1, 3, m.Slice(0, 1)).At(1); leaf != anyText("h") {
+		t.Fatalf("Unexpected leaf %#v", leaf)
+	}
+}
+
+func TestHybridJSON(t *testing.T) {
+	for _, h := range []generic.Hybrid[text]{hybridRaw("hello"), hybridRaw("hello").Append(" world", 6)} {
+		data, err := json.Marshal(h)
+		if err != nil || string(data) != `"`+toStringH(h)+`"` {
+			t.Fatal("Marshal", string(data), err)
+		}
+		x := hybridRaw("")
+		if err := json.Unmarshal(data, &x); err != nil || toStringH(x) != toStringH(h) || (x.Node.Count == 0) != (h.Node.Count == 0) {
+			t.Fatal("Unmarshal", toStringH(x), err)
+		}
+
+		data, err = h.MarshalJSONWith(generic.JSONOptions{Nested: true})
+		if err != nil {
+			t.Fatal("Marshal nested", err)
+		}
+		var y generic.Hybrid[text]
+		if err := json.Unmarshal(data, &y); err != nil || toStringH(y) != toStringH(h) || y.HighMark != h.HighMark {
+			t.Fatal("Unmarshal nested", toStringH(y), string(data), err)
+		}
+		if y = y.Delete(0, 5); toStringH(y) != toStringH(h)[5:] {
+			t.Fatal("Diverged after edit", toStringH(y))
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "slices"
+
+// String is a leaf type for byte strings.  It implements Splicer,
+// Indexer and Hasher.
+type String string
+
+// Slice implements Slicer
+func (s String) Slice(offset, count int) String {
+	return s[offset : offset+count]
+}
+
+// Splice implements Splicer
+func (s String) Splice(offset, count int, replacement String) String {
+	return s[:offset] + replacement + s[offset+count:]
+}
+
+// Index implements Indexer
+func (s String) Index(offset int) byte {
+	return s[offset]
+}
+
+// Hash implements Hasher
+func (s String) Hash() Hash {
+	return HashString(string(s))
+}
+
+// Bytes is a leaf type for byte slices.  It implements Splicer,
+// Indexer and Hasher.
+//
+// Leaves are shared between nodes, so the bytes must not be modified.
+// Slice returns a view of the same bytes (with the capacity capped
+// so appends do not overwrite the rest) while Splice always copies.
+type Bytes []byte
+
+// Slice implements Slicer
+func (b Bytes) Slice(offset, count int) Bytes {
+	return b[offset : offset+count : offset+count]
+}
+
+// Splice implements Splicer
+func (b Bytes) Splice(offset, count int, replacement Bytes) Bytes {
+	return splice(b, offset, count, replacement)
+}
+
+// Index implements Indexer
+func (b Bytes) Index(offset int) byte {
+	return b[offset]
+}
+
+// Hash implements Hasher
+func (b Bytes) Hash() Hash {
+	return HashBytes(b)
+}
+
+// Runes is a leaf type for rune slices.  It implements Splicer,
+// Indexer and Hasher.  Like Bytes, Slice shares the runes and
+// Splice copies them.
+type Runes []rune
+
+// Slice implements Slicer
+func (r Runes) Slice(offset, count int) Runes {
+	return r[offset : offset+count : offset+count]
+}
+
+// Splice implements Splicer
+func (r Runes) Splice(offset, count int, replacement Runes) Runes {
+	return splice(r, offset, count, replacement)
+}
+
+// Index implements Indexer
+func (r Runes) Index(offset int) rune {
+	return r[offset]
+}
+
+// Hash implements Hasher
+func (r Runes) Hash() Hash {
+	var h Hash
+	for _, c := range r {
+		h = h.Append(HashElement(uint64(c)))
+	}
+	return h
+}
+
+// Slice is a leaf type for slices of any element type.  It implements
+// Splicer and Indexer.  Like Bytes, Slice shares the elements and
+// Splice copies them.
+type Slice[E any] []E
+
+// Slice implements Slicer
+func (s Slice[E]) Slice(offset, count int) Slice[E] {
+	return s[offset : offset+count : offset+count]
+}
+
+// Splice implements Splicer
+func (s Slice[E]) Splice(offset, count int, replacement Slice[E]) Slice[E] {
+	return splice(s, offset, count, replacement)
+}
+
+// Index implements Indexer
+func (s Slice[E]) Index(offset int) E {
+	return s[offset]
+}
+
+// splice returns a new slice, never reusing the array of s
+func splice[S ~[]E, E any](s S, offset, count int, replacement S) S {
+	result := make(S, 0, len(s)-count+len(replacement))
+	result = append(result, s[:offset]...)
+	result = append(result, replacement...)
+	return append(result, s[offset+count:]...)
+}
+
+// FromString creates a node holding the bytes of s
+func FromString(s string) Node[String] {
+	return New(String(s), len(s))
+}
+
+// FromBytes creates a node holding a copy of b
+func FromBytes(b []byte) Node[Bytes] {
+	return New(Bytes(slices.Clone(b)), len(b))
+}
+
+// FromSlice creates a node holding a copy of s
+func FromSlice[E any](s []E) Node[Slice[E]] {
+	return New(Slice[E](slices.Clone(s)), len(s))
+}
+
+// HybridFromString creates a hybrid holding the bytes of s with the
+// provided marks
+func HybridFromString(s string, highMark, lowMark int) Hybrid[String] {
+	return newHybrid(String(s), len(s), highMark, lowMark)
+}
+
+// HybridFromBytes creates a hybrid holding a copy of b with the
+// provided marks
+func HybridFromBytes(b []byte, highMark, lowMark int) Hybrid[Bytes] {
+	return newHybrid(Bytes(slices.Clone(b)), len(b), highMark, lowMark)
+}
+
+// HybridFromSlice creates a hybrid holding a copy of s with the
+// provided marks
+func HybridFromSlice[E any](s []E, highMark, lowMark int) Hybrid[Slice[E]] {
+	return newHybrid(Slice[E](slices.Clone(s)), len(s), highMark, lowMark)
+}
+
+// newHybrid starts in Node mode if count is past the high mark
+func newHybrid[T Splicer[T]](v T, count, highMark, lowMark int) Hybrid[T] {
+	if count > highMark {
+		return Hybrid[T]{highMark, lowMark, v.Slice(0, 0), 0, New(v, count)}
+	}
+	return Hybrid[T]{highMark, lowMark, v, count, Node[T]{}}
+}
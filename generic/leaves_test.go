@@ -0,0 +1,91 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func TestLeafTypes(t *testing.T) {
+	rand.Seed(42)
+	str := randomText(500)
+
+	s := generic.FromString("")
+	b := generic.FromBytes(nil)
+	r := generic.HybridFromSlice([]rune(nil), 100, 50)
+	x := generic.HybridFromSlice([]byte(nil), 100, 50)
+	for kk := 0; kk < 500; kk++ {
+		offset := rand.Intn(s.Count + 1)
+		insert := str[kk : kk+1]
+		s = s.Splice(offset, 0, generic.FromString(insert))
+		b = b.Splice(offset, 0, generic.FromBytes([]byte(insert)))
+		r = r.Splice(offset, 0, generic.HybridFromSlice([]rune(insert), 100, 50))
+		x = x.Splice(offset, 0, generic.HybridFromSlice([]byte(insert), 100, 50))
+		if kk%10 == 0 {
+			count := rand.Intn(s.Count - offset + 1)
+			s = s.Delete(offset, count)
+			b = b.Delete(offset, count)
+			r = r.Splice(offset, count, generic.HybridFromSlice([]rune(nil), 100, 50))
+			x = x.Splice(offset, count, generic.HybridFromSlice([]byte(nil), 100, 50))
+		}
+	}
+
+	expected := ""
+	s.ForEach(func(v generic.String, count int) { expected += string(v) })
+	got := []string{"", "", ""}
+	b.ForEach(func(v generic.Bytes, count int) { got[0] += string(v) })
+	r.ForEach(func(v generic.Slice[rune], count int) { got[1] += string(v) })
+	x.ForEach(func(v generic.Slice[byte], count int) { got[2] += string(v) })
+	for _, g := range got {
+		if g != expected {
+			t.Fatal("Diverged", g, expected)
+		}
+	}
+	if !generic.Equal(generic.FromString(expected), s) {
+		t.Fatal("Unexpected hash")
+	}
+}
+
+func TestLeafCopies(t *testing.T) {
+	buf := []byte("hello world")
+	n := generic.FromBytes(buf)
+	buf[0] = 'j'
+	if string(n.Leaf) != "hello world" {
+		t.Fatal("FromBytes did not copy", string(n.Leaf))
+	}
+
+	s := generic.Slice[int]{1, 2, 3, 4}
+	sliced := s.Slice(0, 2)
+	if cap(sliced) != 2 {
+		t.Fatal("Unexpected capacity", cap(sliced))
+	}
+	_ = append(sliced, 10)
+	spliced := s.Splice(1, 1, generic.Slice[int]{5})
+	spliced[0] = 10
+	if s[0] != 1 || s[2] != 3 || spliced[1] != 5 {
+		t.Fatal("Unexpected aliasing", s, spliced)
+	}
+
+	r := generic.Runes("héllo")
+	if r.Splice(1, 1, generic.Runes("e")).Index(1) != 'e' || r.Index(1) != 'é' {
+		t.Fatal("Unexpected runes", string(r))
+	}
+}
+
+func TestHybridFrom(t *testing.T) {
+	h := generic.HybridFromString("hello", 3, 2)
+	if h.Node.Count != 5 || h.Size() != 5 {
+		t.Fatal("Expected Node mode", h)
+	}
+	h = generic.HybridFromString("hello", 10, 5)
+	if h.Node.Count != 0 || h.Count != 5 || h.Raw.(generic.String) != "hello" {
+		t.Fatal("Expected Raw mode", h)
+	}
+	if hh, _ := h.Hash(); hh != generic.HashString("hello") {
+		t.Fatal("Unexpected hash", hh)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import "sort"
+
+// Conflict describes a region of the base changed by both sides of a
+// Merge.  Offset and Count refer to the base while Base, Ours and
+// Theirs hold the contents of the region in each of the versions.
+type Conflict[T any] struct {
+	Offset, Count      int
+	Base, Ours, Theirs Node[T]
+}
+
+// Merge combines the changes made to base by ours and theirs.  All
+// three must belong to the same Forest.  Changes that do not overlap
+// are all applied.  Overlapping ones are conflicts: the provided
+// resolve function picks the contents of the region instead.  If
+// resolve is nil, ours wins.  The conflicts are also returned.
+//
+// Much like Diff, Merge uses the node IDs to skip the subtrees shared
+// by the versions.  The edits are narrowed to the elements that
+// changed (see Diff), so edits within the same leaf only overlap if
+// the elements they change do.  Inserts at the same offset or at the boundary of
+// a region deleted by the other side are considered overlapping.  An
+// edit made by both sides (the same region replaced with Equal
+// contents) is not a conflict and is applied once.
+func Merge[T any](base, ours, theirs Node[T], resolve func(Conflict[T]) Node[T]) (Node[T], []Conflict[T]) {
+	edits := append(baseEdits(Diff(base, ours), 0), baseEdits(Diff(base, theirs), 1)...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var clusters [][]baseEdit[T]
+	for _, e := range edits {
+		last := len(clusters) - 1
+		if last >= 0 && overlaps(clusters[last], e) {
+			clusters[last] = append(clusters[last], e)
+		} else {
+			clusters = append(clusters, []baseEdit[T]{e})
+		}
+	}
+
+	var conflicts []Conflict[T]
+	var replacements []Node[T]
+	shifts := [2]int{}
+	for _, cluster := range clusters {
+		start, end := span(cluster)
+		var sides [2][]baseEdit[T]
+		for _, e := range cluster {
+			sides[e.side] = append(sides[e.side], e)
+		}
+
+		var replacement Node[T]
+		switch {
+		case len(cluster) == 2 && cluster[0].same(cluster[1]):
+			replacement = cluster[0].insert
+		case len(sides[1]) == 0:
+			replacement = side(ours, sides[0], start, end, shifts[0])
+		case len(sides[0]) == 0:
+			replacement = side(theirs, sides[1], start, end, shifts[1])
+		default:
+			c := Conflict[T]{Offset: start, Count: end - start, Base: base.Slice(start, end-start)}
+			c.Ours = side(ours, sides[0], start, end, shifts[0])
+			c.Theirs = side(theirs, sides[1], start, end, shifts[1])
+			replacement = c.Ours
+			if resolve != nil {
+				replacement = resolve(c)
+			}
+			conflicts = append(conflicts, c)
+		}
+		replacements = append(replacements, replacement)
+
+		for kk := range sides {
+			for _, e := range sides[kk] {
+				shifts[kk] += e.insert.Count - (e.end - e.start)
+			}
+		}
+	}
+
+	for kk := len(clusters) - 1; kk >= 0; kk-- {
+		start, end := span(clusters[kk])
+		base = base.Splice(start, end-start, replacements[kk])
+	}
+	return base, conflicts
+}
+
+// baseEdit is an edit with the offsets referring to the base
+type baseEdit[T any] struct {
+	start, end int
+	insert     Node[T]
+	side       int
+}
+
+func (e baseEdit[T]) same(o baseEdit[T]) bool {
+	return e.start == o.start && e.end == o.end && Equal(e.insert, o.insert)
+}
+
+// baseEdits converts the result of Diff to use base offsets
+func baseEdits[T any](edits []Edit[T], side int) []baseEdit[T] {
+	result := make([]baseEdit[T], len(edits))
+	shift := 0
+	for kk, e := range edits {
+		start := e.Offset - shift
+		result[kk] = baseEdit[T]{start, start + e.Count, e.Insert, side}
+		shift += e.Insert.Count - e.Count
+	}
+	return result
+}
+
+func overlaps[T any](cluster []baseEdit[T], e baseEdit[T]) bool {
+	start, end := span(cluster)
+	if start == end || e.start == e.end {
+		return e.start <= end
+	}
+	return e.start < end
+}
+
+// span returns the region of the base covered by the edits
+func span[T any](edits []baseEdit[T]) (int, int) {
+	start, end := edits[0].start, edits[0].end
+	for _, e := range edits[1:] {
+		start, end = min(start, e.start), max(end, e.end)
+	}
+	return start, end
+}
+
+// side returns the contents of the base region start:end in version
+// given the edits of the version within the region and the shift
+// caused by the edits of the version before the region
+func side[T any](version Node[T], edits []baseEdit[T], start, end, shift int) Node[T] {
+	count := end - start
+	for _, e := range edits {
+		count += e.insert.Count - (e.end - e.start)
+	}
+	return version.Slice(start+shift, count)
+}
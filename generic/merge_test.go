@@ -0,0 +1,180 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for _, word := range []string{"the ", "quick ", "brown ", "fox ", "jumps ", "over ", "the ", "lazy ", "dog"} {
+		base = base.Append(text(word), len(word))
+	}
+	f := base.Forest()
+
+	ours := base.Splice(4, 6, f.New("slow ", 5)).Append("!", 1)
+	theirs := base.Splice(16, 4, f.New("cat ", 4)).Delete(0, 4)
+	merged, conflicts := generic.Merge(base, ours, theirs, nil)
+	if x := toString(merged); x != "slow brown cat jumps over the lazy dog!" || len(conflicts) != 0 {
+		t.Fatal("Unexpected merge", x, conflicts)
+	}
+
+	theirs = base.Splice(4, 6, f.New("fast ", 5))
+	merged, conflicts = generic.Merge(base, ours, theirs, func(c generic.Conflict[text]) generic.Node[text] {
+		// the space shared by all three is not part of the conflict
+		if toString(c.Base) != "quick" || toString(c.Ours) != "slow" || toString(c.Theirs) != "fast" {
+			t.Fatal("Unexpected conflict", toString(c.Base), toString(c.Ours), toString(c.Theirs))
+		}
+		return c.Theirs.Append(" ", 1).Concat(c.Ours)
+	})
+	if x := toString(merged); x != "the fast slow brown fox jumps over the lazy dog!" || len(conflicts) != 1 || conflicts[0].Offset != 4 || conflicts[0].Count != 5 {
+		t.Fatal("Unexpected merge", x, conflicts)
+	}
+
+	merged, conflicts = generic.Merge(base, ours, ours, nil)
+	if toString(merged) != toString(ours) || len(conflicts) != 0 {
+		t.Fatal("Unexpected merge of same edits", toString(merged), conflicts)
+	}
+
+	same := func() generic.Node[text] { return base.Splice(6, 2, f.New("ee", 2)) }
+	merged, conflicts = generic.Merge(base, same(), same(), nil)
+	if toString(merged) != "the queek brown fox jumps over the lazy dog" || len(conflicts) != 0 {
+		t.Fatal("Unexpected merge of identical edits", toString(merged), conflicts)
+	}
+
+	merged, conflicts = generic.Merge(base, base.Delete(4, 6), base.Delete(10, 6), nil)
+	if toString(merged) != "the fox jumps over the lazy dog" || len(conflicts) != 0 {
+		t.Fatal("Unexpected merge of adjacent deletes", toString(merged), conflicts)
+	}
+
+	merged, conflicts = generic.Merge(base, base.Delete(4, 6), base.Insert(10, "red ", 4), nil)
+	if toString(merged) != "the brown fox jumps over the lazy dog" || len(conflicts) != 1 {
+		t.Fatal("Unexpected merge of insert at the edge of a delete", toString(merged), conflicts)
+	}
+
+	// edits within the same leaf which do not overlap are both applied
+	merged, conflicts = generic.Merge(base, base.Splice(4, 1, f.New("Q", 1)), base.Splice(7, 2, f.New("CK", 2)), nil)
+	if toString(merged) != "the QuiCK brown fox jumps over the lazy dog" || len(conflicts) != 0 {
+		t.Fatal("Unexpected merge of edits within a leaf", toString(merged), conflicts)
+	}
+
+	merged, conflicts = generic.Merge(base, base.Insert(4, "very ", 5), base.Insert(4, "so ", 3), nil)
+	if toString(merged) != "the very quick brown fox jumps over the lazy dog" || len(conflicts) != 1 {
+		t.Fatal("Unexpected merge of inserts", toString(merged), conflicts)
+	}
+}
+
+func TestMergeRandom(t *testing.T) {
+	rand.Seed(42)
+	base := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < 40; kk++ {
+		base = base.Append(text(randomText(5)), 5)
+	}
+	f := base.Forest()
+	str := toString(base)
+
+	// a single leaf holding everything, so that all edits are within
+	// the same leaf
+	leaf := f.New(text(str), len(str))
+
+	for kk := 0; kk < 400; kk++ {
+		// edits apart from each other do not conflict, whether or
+		// not they touch the same leaf.  The changes of a side within
+		// a leaf are narrowed as a whole, so within the single leaf
+		// all of ours come before all of theirs.
+		b, single := base, kk%2 == 1
+		if single {
+			b = leaf
+		}
+		ours, theirs, expected := b, b, str
+		regions := randomRegions(base.Count, 11)
+		for jj := len(regions) - 1; jj >= 0; jj-- {
+			start, end := regions[jj][0], regions[jj][1]
+			insert := randomText(rand.Intn(4))
+			if start == end && insert == "" {
+				insert = "x"
+			}
+			if single && jj < len(regions)/2 || !single && rand.Intn(2) == 0 {
+				ours = ours.Splice(start, end-start, f.New(text(insert), len(insert)))
+			} else {
+				theirs = theirs.Splice(start, end-start, f.New(text(insert), len(insert)))
+			}
+			expected = expected[:start] + insert + expected[end:]
+		}
+
+		merged, conflicts := generic.Merge(b, ours, theirs, func(c generic.Conflict[text]) generic.Node[text] {
+			t.Fatal("Unexpected conflict", c.Offset, c.Count)
+			return c.Ours
+		})
+		if x := toString(merged); x != expected || len(conflicts) != 0 {
+			t.Fatal("Diverged", x, expected)
+		}
+	}
+
+	for kk := 0; kk < 200; kk++ {
+		start := rand.Intn(base.Count)
+		end := start + rand.Intn(min(10, base.Count-start))
+		// upper case inserts share no elements with the base, so
+		// the edits are not narrowed away from each other
+		ours := base.Splice(start, end-start, f.New(text("OURS"), 4))
+
+		if end > start {
+			start += rand.Intn(end - start)
+		}
+		end = start + rand.Intn(base.Count-start+1)/4
+		insert := text(strings.ToUpper(randomText(1 + rand.Intn(3))))
+		theirs := base.Splice(start, end-start, f.New(insert, len(insert)))
+
+		var replacement generic.Node[text]
+		calls := 0
+		merged, conflicts := generic.Merge(base, ours, theirs, func(c generic.Conflict[text]) generic.Node[text] {
+			calls++
+			switch kk % 3 {
+			case 0:
+				replacement = c.Theirs.Concat(c.Ours)
+			case 1:
+				replacement = c.Base.Slice(0, 0)
+			default:
+				replacement = c.Theirs
+			}
+			return replacement
+		})
+		if calls != 1 || len(conflicts) != 1 {
+			t.Fatal("Expected a conflict", calls, len(conflicts))
+		}
+
+		c := conflicts[0]
+		before, after := str[:c.Offset], str[c.Offset+c.Count:]
+		switch {
+		case toString(c.Base) != str[c.Offset:c.Offset+c.Count]:
+			t.Fatal("Unexpected base", toString(c.Base))
+		case before+toString(c.Ours)+after != toString(ours):
+			t.Fatal("Unexpected ours", toString(c.Ours))
+		case before+toString(c.Theirs)+after != toString(theirs):
+			t.Fatal("Unexpected theirs", toString(c.Theirs))
+		case toString(merged) != before+toString(replacement)+after:
+			t.Fatal("Unexpected merge", toString(merged))
+		}
+	}
+}
+
+// randomRegions returns sorted regions within size that are at least
+// gap apart
+func randomRegions(size, gap int) [][2]int {
+	var result [][2]int
+	for pos := rand.Intn(gap); ; {
+		start := pos + rand.Intn(20)
+		if start > size {
+			return result
+		}
+		end := min(start+rand.Intn(6), size)
+		result = append(result, [2]int{start, end})
+		pos = end + gap
+	}
+}
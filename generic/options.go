@@ -0,0 +1,162 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+// Threshold at which node height is increased in favor of creating
+// larger chlidren array.  This threshold is very likely dependent on
+// hardware and such but the number is high enough for this to be rare
+const limit = 100
+
+// Options controls the shape of the tree.  The zero value of each
+// field picks the default behavior.
+//
+// Leaf sizes only matter if the leaf values implement Splicer (to
+// merge leaves) and Slicer (to split them).  Leaves that don't are
+// left as is.
+type Options struct {
+	// MaxFanout is the number of children beyond which edits add a
+	// level rather than grow the children of a node.  It defaults
+	// to 100.
+	MaxFanout int
+
+	// MinFanout is the smallest number of children a non-root
+	// internal node of a Balanced tree has.  It defaults to half of
+	// MaxFanout.
+	MinFanout int
+
+	// Balanced makes Splice, Slice and Flatten maintain the B-tree
+	// shape described in Rebalance.
+	Balanced bool
+
+	// LeafSize is the leaf size that Flatten aims for: larger leaves
+	// are split and adjacent smaller leaves are merged.  It also caps
+	// the size of leaves merged due to MinLeafSize.
+	LeafSize int
+
+	// MinLeafSize is the size below which a leaf is merged with its
+	// neighbor when they are joined by an edit.
+	MinLeafSize int
+
+	// FlattenDepth is the depth beyond which Splice automatically
+	// flattens the tree.  It is ignored for Balanced trees.
+	FlattenDepth int
+}
+
+func (o Options) normalize() Options {
+	if o.MaxFanout == 0 {
+		o.MaxFanout = limit
+	}
+	if o.MinFanout == 0 {
+		o.MinFanout = (o.MaxFanout + 1) / 2
+	}
+	if o.MaxFanout < 2 || o.Balanced && !validFanout(o.MinFanout, o.MaxFanout) {
+		panic("Unexpected fan-out")
+	}
+	if o.LeafSize < 0 || o.MinLeafSize < 0 || o.FlattenDepth < 0 {
+		panic("Unexpected options")
+	}
+	return o
+}
+
+func validFanout(minFanout, maxFanout int) bool {
+	return minFanout >= 2 && maxFanout >= 2*minFanout-1
+}
+
+// Options returns the options in effect for the node
+func (n Node[T]) Options() Options {
+	if n.opts == nil {
+		return Options{}.normalize()
+	}
+	return *n.opts
+}
+
+// WithOptions returns the node with the provided options.  The tree
+// is rebalanced if the options are Balanced.
+func (n Node[T]) WithOptions(opts Options) Node[T] {
+	opts = opts.normalize()
+	n.opts = &opts
+	if opts.Balanced {
+		return n.rebalance(opts.MaxFanout)
+	}
+	return n
+}
+
+// mergeLeaves combines two leaf nodes into one if either is smaller
+// than minSize and the result does not exceed LeafSize.
+func (n Node[T]) mergeLeaves(a, b Node[T], minSize int) (Node[T], bool) {
+	opts := n.Options()
+	switch {
+	case a.Children != nil || b.Children != nil:
+		return a, false
+	case a.Count >= minSize && b.Count >= minSize:
+		return a, false
+	case opts.LeafSize > 0 && a.Count+b.Count > opts.LeafSize:
+		return a, false
+	}
+
+	s, ok := any(a.value()).(Splicer[T])
+	if !ok {
+		return a, false
+	}
+	a.ids, a.opts = n.ids, n.opts
+	a = a.fresh()
+	a.Leaf, a.lazy = s.Splice(a.Count, 0, b.value()), nil
+	a.Count += b.Count
+	return a, true
+}
+
+// forEachResized is like forEach but leaves are resized towards the
+// LeafSize option
+func (n Node[T]) forEachResized(fn func(n Node[T])) {
+	size := n.Options().LeafSize
+	if size == 0 {
+		n.forEach(fn)
+		return
+	}
+
+	pending := Node[T]{}
+	n.forEach(func(leaf Node[T]) {
+		if _, ok := any(leaf.value()).(Slicer[T]); ok {
+			for leaf.Count > size {
+				if pending.Count > 0 {
+					fn(pending)
+					pending = Node[T]{}
+				}
+				fn(leaf.sliceLeaf(0, size))
+				leaf = leaf.sliceLeaf(size, leaf.Count-size)
+			}
+		}
+
+		if pending.Count == 0 {
+			pending = leaf
+			return
+		}
+		if merged, ok := n.mergeLeaves(pending, leaf, size); ok {
+			pending = merged
+			return
+		}
+		fn(pending)
+		pending = leaf
+	})
+	if pending.Count > 0 {
+		fn(pending)
+	}
+}
+
+// depthAt returns the number of levels along the path to the
+// element at (or just before) the provided offset
+func (n Node[T]) depthAt(offset int) int {
+	if offset >= n.Count {
+		offset = n.Count - 1
+	}
+
+	depth := 0
+	for ; n.Children != nil && offset >= 0; depth++ {
+		var kk int
+		kk, offset = n.child(offset)
+		n = n.Children[kk]
+	}
+	return depth
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"testing"
+)
+
+func TestOptionsDefaults(t *testing.T) {
+	opts := generic.New(text(""), 0).Options()
+	if opts.MaxFanout != 100 || opts.Balanced || opts.LeafSize != 0 {
+		t.Fatal("Unexpected defaults", opts)
+	}
+
+	opts = generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 8}).Options()
+	if opts.MaxFanout != 8 || opts.MinFanout != 4 {
+		t.Fatal("Unexpected options", opts)
+	}
+
+	mustPanic(t, func() { generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 1}) })
+	mustPanic(t, func() { generic.NewWithOptions(text(""), 0, generic.Options{LeafSize: -1}) })
+	mustPanic(t, func() {
+		generic.NewWithOptions(text(""), 0, generic.Options{Balanced: true, MinFanout: 3, MaxFanout: 4})
+	})
+}
+
+func TestMaxFanout(t *testing.T) {
+	n := generic.NewWithOptions(text("x"), 1, generic.Options{MaxFanout: 3})
+	str := "x"
+	for kk := 0; kk < 100; kk++ {
+		s := text(randomText(1))
+		offset := []int{0, len(str), len(str) / 2}[kk%3]
+		n = n.Splice(offset, 0, generic.New(s, 1))
+		str = str[:offset] + string(s) + str[offset:]
+		if x := toString(n); x != str {
+			t.Fatal("Diverged", x, str)
+		}
+	}
+
+	var check func(n generic.Node[text])
+	check = func(n generic.Node[text]) {
+		if len(n.Children) > 3 {
+			t.Fatal("Unexpected fan-out", len(n.Children))
+		}
+		for _, child := range n.Children {
+			check(child)
+		}
+	}
+	check(n)
+}
+
+func TestLeafSizes(t *testing.T) {
+	opts := generic.Options{MinLeafSize: 4, LeafSize: 8}
+	n := generic.NewWithOptions(text(""), 0, opts)
+	str := ""
+	for kk := 0; kk < 100; kk++ {
+		n = n.Splice(n.Count, 0, generic.New(text("a"), 1))
+		str += "a"
+	}
+	if x := toString(n); x != str {
+		t.Fatal("Diverged", x)
+	}
+
+	leaves := 0
+	for leaf, count := range n.Leaves() {
+		if count > 8 || len(leaf) != count {
+			t.Fatal("Unexpected leaf", leaf, count)
+		}
+		leaves++
+	}
+	if leaves > 100/4 {
+		t.Fatal("Leaves were not merged", leaves)
+	}
+
+	long := generic.NewWithOptions(text(str), len(str), generic.Options{LeafSize: 6})
+	long = long.Splice(10, 0, generic.New(text("b"), 1)).Flatten(0)
+	if x := toString(long); x != str[:10]+"b"+str[10:] {
+		t.Fatal("Flatten diverged", x)
+	}
+	for leaf, count := range long.Leaves() {
+		if count > 6 || len(leaf) != count {
+			t.Fatal("Unexpected leaf", leaf, count)
+		}
+	}
+}
+
+func TestFlattenDepth(t *testing.T) {
+	rand.Seed(42)
+	n := generic.NewWithOptions(text("hello"), 5, generic.Options{FlattenDepth: 4})
+	str := "hello"
+	for kk := 0; kk < 500; kk++ {
+		offset := rand.Intn(len(str) + 1)
+		count := rand.Intn(len(str) - offset + 1)
+		if count > 3 {
+			count = 3
+		}
+		r := text(randomText(3))
+		n = n.Splice(offset, count, generic.New(r, 3))
+		str = str[:offset] + string(r) + str[offset+count:]
+		if x := toString(n); x != str {
+			t.Fatal("Diverged", x, str)
+		}
+		if _, _, path := n.Locate(offset); len(path) > 4 {
+			t.Fatal("Depth exceeded", len(path))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentEdits(t *testing.T) {
+	root := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < 100; kk++ {
+		root = root.Append(text(randomText(5)), 5)
+	}
+	str := toString(root)
+
+	var wg sync.WaitGroup
+	results := make([]generic.Node[text], 16)
+	for kk := range results {
+		wg.Add(1)
+		go func(kk int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(kk)))
+			n := root
+			for jj := 0; jj < 200; jj++ {
+				offset := r.Intn(n.Count + 1)
+				count := r.Intn(n.Count - offset + 1)
+				switch r.Intn(5) {
+				case 0:
+					n = n.Slice(offset, count)
+				case 1:
+					n = n.Splice(offset, count, root.Slice(0, r.Intn(10)))
+				case 2:
+					n = n.Flatten(0)
+				case 3:
+					n = n.Append("x", 1)
+				default:
+					n = root.Prepend("y", 1)
+				}
+			}
+			results[kk] = n
+		}(kk)
+	}
+	wg.Wait()
+
+	if x := toString(root); x != str {
+		t.Fatal("Root changed", x, str)
+	}
+	ids := map[int]bool{}
+	for _, n := range results {
+		if err := n.Validate(); err != nil {
+			t.Fatal("Invalid", err)
+		}
+		if ids[n.ID] {
+			t.Fatal("Duplicate ID across goroutines", n.ID)
+		}
+		ids[n.ID] = true
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+// Stats describes the shape of a tree.  It is meant to help decide
+// when to Flatten or Rebalance.
+type Stats struct {
+	// Depth is the length of the longest path from the root to a
+	// leaf.  A tree with just one leaf has depth zero.
+	Depth int
+
+	// Internal and Leaves are the number of internal and non-empty
+	// leaf nodes respectively.
+	Internal, Leaves int
+
+	// Empty is the number of nodes with zero count.
+	Empty int
+
+	// MinLeaf, MaxLeaf and AvgLeaf are the leaf sizes (as in Count)
+	// of the non-empty leaves.
+	MinLeaf, MaxLeaf int
+	AvgLeaf          float64
+
+	// MaxChildren is the largest number of children of any node.
+	MaxChildren int
+}
+
+// HybridStats is the Stats of a Hybrid along with the storage mode.
+type HybridStats struct {
+	Raw bool
+	Stats
+}
+
+// Stats walks the whole tree and collects the Stats.
+func (n Node[T]) Stats() Stats {
+	s := Stats{}
+	total := 0
+	n.stats(0, &s, &total)
+	if s.Leaves > 0 {
+		s.AvgLeaf = float64(total) / float64(s.Leaves)
+	}
+	return s
+}
+
+func (n Node[T]) stats(depth int, s *Stats, total *int) {
+	if depth > s.Depth {
+		s.Depth = depth
+	}
+
+	switch {
+	case n.Count == 0:
+		s.Empty++
+	case n.Children == nil:
+		if s.Leaves == 0 || n.Count < s.MinLeaf {
+			s.MinLeaf = n.Count
+		}
+		if n.Count > s.MaxLeaf {
+			s.MaxLeaf = n.Count
+		}
+		s.Leaves++
+		*total += n.Count
+		return
+	}
+
+	if n.Children != nil {
+		s.Internal++
+	}
+	if len(n.Children) > s.MaxChildren {
+		s.MaxChildren = len(n.Children)
+	}
+	for _, child := range n.Children {
+		child.stats(depth+1, s, total)
+	}
+}
+
+// Stats returns the Stats of the underlying Node or that of a
+// single leaf in Raw mode.
+func (h Hybrid[T]) Stats() HybridStats {
+	if h.Node.Count > 0 {
+		return HybridStats{false, h.Node.Stats()}
+	}
+
+	if h.Count == 0 {
+		return HybridStats{true, Stats{Empty: 1}}
+	}
+	return HybridStats{true, Stats{
+		Leaves:  1,
+		MinLeaf: h.Count,
+		MaxLeaf: h.Count,
+		AvgLeaf: float64(h.Count),
+	}}
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	if s := generic.New(text("hello"), 5).Stats(); s != (generic.Stats{Leaves: 1, MinLeaf: 5, MaxLeaf: 5, AvgLeaf: 5}) {
+		t.Fatal("Leaf stats", s)
+	}
+
+	if s := generic.New(text(""), 0).Stats(); s != (generic.Stats{Empty: 1}) {
+		t.Fatal("Empty stats", s)
+	}
+
+	n := generic.New(text("hello"), 5).
+		Splice(5, 0, generic.New(text(" world"), 6)).
+		Splice(0, 0, generic.New(text(">"), 1)).
+		Flatten(2)
+	expected := generic.Stats{
+		Depth:       2,
+		Internal:    3,
+		Leaves:      3,
+		MinLeaf:     1,
+		MaxLeaf:     6,
+		AvgLeaf:     4,
+		MaxChildren: 2,
+	}
+	if s := n.Stats(); s != expected {
+		t.Fatal("Tree stats", s)
+	}
+}
+
+func TestHybridStats(t *testing.T) {
+	if s := hybridRaw("hello").Stats(); !s.Raw || s.Leaves != 1 || s.MaxLeaf != 5 {
+		t.Fatal("Raw stats", s)
+	}
+
+	if s := hybridRaw("").Stats(); !s.Raw || s.Empty != 1 {
+		t.Fatal("Empty raw stats", s)
+	}
+
+	h := hybridRaw("hello").Splice(5, 0, hybridRaw(" world"))
+	if s := h.Stats(); s.Raw || s.Leaves != 2 || s.Depth != 1 {
+		t.Fatal("Node stats", s)
+	}
+}
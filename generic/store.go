@@ -0,0 +1,447 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Key is the content hash (SHA-256) of a node saved to a Store.  The
+// key of an internal node covers the keys of its children, so equal
+// keys mean equal subtrees.
+type Key [sha256.Size]byte
+
+func (k Key) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// ParseKey parses the result of Key.String
+func ParseKey(s string) (Key, error) {
+	var k Key
+	b, err := hex.DecodeString(s)
+	if err == nil && len(b) != len(k) {
+		err = fmt.Errorf("trope: invalid key %q", s)
+	}
+	copy(k[:], b)
+	return k, err
+}
+
+// ErrNotFound is returned by Store.Get for missing keys
+var ErrNotFound = errors.New("trope: not found")
+
+// Store holds blobs by their key.  Put is only ever called with the
+// SHA-256 of the data as the key, so putting an existing key can be
+// skipped.  Stores must be safe for concurrent use.
+type Store interface {
+	Get(key Key) ([]byte, error)
+	Put(key Key, data []byte) error
+}
+
+// NewMemStore creates an empty in-memory Store
+func NewMemStore() Store {
+	return &memStore{blobs: map[Key][]byte{}}
+}
+
+type memStore struct {
+	sync.RWMutex
+	blobs map[Key][]byte
+}
+
+func (s *memStore) Get(key Key) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if data, ok := s.blobs[key]; ok {
+		return data, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memStore) Put(key Key, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.blobs[key]; !ok {
+		s.blobs[key] = append([]byte(nil), data...)
+	}
+	return nil
+}
+
+// NewFileStore creates a Store which keeps each blob in its own file
+// within dir.  The directory is created if needed.
+func NewFileStore(dir string) (Store, error) {
+	return fileStore(dir), os.MkdirAll(dir, 0o755)
+}
+
+type fileStore string
+
+func (s fileStore) path(key Key) string {
+	name := key.String()
+	return filepath.Join(string(s), name[:2], name[2:])
+}
+
+func (s fileStore) Get(key Key) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		err = ErrNotFound
+	}
+	return data, err
+}
+
+func (s fileStore) Put(key Key, data []byte) error {
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file so that readers never see a
+	// partially written blob
+	f, err := os.CreateTemp(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// NodeStore saves nodes to a Store, one blob per node.  Subtrees are
+// keyed by content, so versions of a tree share the blobs of all the
+// subtrees they have in common.
+//
+// Loading a version reads its internal nodes but not the contents of
+// its leaves: the blob of an internal node holds the counts of its
+// children, so a loaded node has Children filled in and its leaves
+// have their Count but read their Leaf on first use (see
+// Node.Resolve).  Only the leaves of a version that are used get
+// read.
+//
+// Saved and loaded nodes remember their keys, so saving a version
+// derived from a saved or loaded version only visits the nodes that
+// changed.  A NodeStore also keeps a bounded number of the most
+// recently used nodes and loads these keys to the same nodes, so the
+// versions loaded together share the subtrees they have in common
+// and can be compared with Diff or merged with Merge.  Options are
+// not saved.
+type NodeStore[T any] struct {
+	store Store
+	codec LeafCodec[T]
+	ids   *IDSource
+
+	mu     sync.Mutex
+	recent *list.List // of storedNode[T], most recently used first
+	nodes  map[Key]*list.Element
+}
+
+// recentNodes is the number of recently used nodes a NodeStore keeps
+const recentNodes = 4096
+
+// storedNode is a node along with its key
+type storedNode[T any] struct {
+	key  Key
+	node Node[T]
+}
+
+// NewNodeStore creates a NodeStore backed by store
+func NewNodeStore[T any](store Store, codec LeafCodec[T]) *NodeStore[T] {
+	return NewNodeStoreWithIDs(store, codec, &IDSource{})
+}
+
+// NewNodeStoreWithIDs is like NewNodeStore but the nodes loaded get
+// their IDs from the provided source.  IDs are not saved, so use a
+// source with RandomIDs or TimeOrderedIDs for loaded nodes to have
+// UIDs that are unique across processes.
+func NewNodeStoreWithIDs[T any](store Store, codec LeafCodec[T], ids *IDSource) *NodeStore[T] {
+	return &NodeStore[T]{
+		store:  store,
+		codec:  codec,
+		ids:    ids,
+		recent: list.New(),
+		nodes:  map[Key]*list.Element{},
+	}
+}
+
+// Save saves root and all its descendants, returning the key of root.
+// Leaves loaded by a NodeStore which have not been read yet are read
+// first and the error is returned if that fails.
+func (s *NodeStore[T]) Save(root Node[T]) (Key, error) {
+	if key, ok := root.load(s); ok {
+		return key.(Key), nil
+	}
+
+	var buf []byte
+	if root.Children == nil {
+		resolved, err := root.Resolve()
+		if err != nil {
+			return Key{}, err
+		}
+		data, err := s.codec.MarshalLeaf(resolved.Leaf)
+		if err != nil {
+			return Key{}, err
+		}
+		buf = binary.AppendVarint(buf, tagLeaf)
+		buf = binary.AppendVarint(buf, int64(root.Count))
+		buf = append(buf, data...)
+	} else {
+		buf = binary.AppendVarint(buf, tagInternal)
+		buf = binary.AppendVarint(buf, int64(root.Count))
+		for _, child := range root.Children {
+			key, err := s.Save(child)
+			if err != nil {
+				return Key{}, err
+			}
+			tag := int64(tagInternal)
+			if child.Children == nil {
+				tag = tagLeaf
+			}
+			buf = binary.AppendVarint(buf, tag)
+			buf = binary.AppendVarint(buf, int64(child.Count))
+			buf = append(buf, key[:]...)
+		}
+	}
+
+	key := Key(sha256.Sum256(buf))
+	if err := s.store.Put(key, buf); err != nil {
+		return Key{}, err
+	}
+	root.store(s, key)
+	if root.ids == s.ids {
+		s.remember(key, root)
+	}
+	return key, nil
+}
+
+// Load returns the node saved with the provided key.  The internal
+// nodes are read right away but the contents of the leaves are read
+// when first used.  Load returns ErrNotFound if a node is missing.
+func (s *NodeStore[T]) Load(key Key) (Node[T], error) {
+	if n, ok := s.recall(key); ok {
+		return n, nil
+	}
+
+	tag, count, rest, err := s.read(key)
+	if err != nil {
+		return Node[T]{}, err
+	}
+
+	n := Node[T]{ids: s.ids, Count: count}
+	switch tag {
+	case tagLeaf:
+		if n.Leaf, err = s.codec.UnmarshalLeaf(rest); err != nil {
+			return Node[T]{}, err
+		}
+	case tagInternal:
+		if n.Children, err = s.loadChildren(key, count, rest); err != nil {
+			return Node[T]{}, err
+		}
+	default:
+		return Node[T]{}, fmt.Errorf("trope: corrupt blob %v", key)
+	}
+
+	n = n.fresh()
+	n.store(s, key)
+	return s.remember(key, n), nil
+}
+
+// read reads the blob with the provided key, returning its tag, its
+// count and the rest of the blob
+func (s *NodeStore[T]) read(key Key) (tag int64, count int, rest []byte, err error) {
+	data, err := s.store.Get(key)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if Key(sha256.Sum256(data)) != key {
+		return 0, 0, nil, fmt.Errorf("trope: corrupt blob %v", key)
+	}
+
+	r := bytes.NewReader(data)
+	tag, err = binary.ReadVarint(r)
+	if err != nil {
+		return 0, 0, nil, unexpectedEOF(err)
+	}
+	n, err := binary.ReadVarint(r)
+	if err != nil {
+		return 0, 0, nil, unexpectedEOF(err)
+	}
+	if n < 0 {
+		return 0, 0, nil, fmt.Errorf("trope: invalid count in blob %v", key)
+	}
+	return tag, int(n), data[len(data)-r.Len():], nil
+}
+
+// loadChildren loads the children of the internal node with the
+// provided key from the tags, counts and keys of the children.  Leaf
+// children are loaded without their contents.
+func (s *NodeStore[T]) loadChildren(key Key, count int, entries []byte) ([]Node[T], error) {
+	children := []Node[T]{}
+	total := 0
+	r := bytes.NewReader(entries)
+	for r.Len() > 0 {
+		tag, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		n, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		var childKey Key
+		if n <= 0 || r.Len() < len(childKey) {
+			return nil, fmt.Errorf("trope: corrupt blob %v", key)
+		}
+		r.Read(childKey[:])
+
+		var child Node[T]
+		switch tag {
+		case tagLeaf:
+			child = s.loadLeaf(childKey, int(n))
+		case tagInternal:
+			if child, err = s.Load(childKey); err != nil {
+				return nil, err
+			}
+			if child.Children == nil || child.Count != int(n) {
+				return nil, fmt.Errorf("trope: invalid count in blob %v", key)
+			}
+		default:
+			return nil, fmt.Errorf("trope: corrupt blob %v", key)
+		}
+		children = append(children, child)
+		total += child.Count
+	}
+	if total != count {
+		return nil, fmt.Errorf("trope: invalid count in blob %v", key)
+	}
+	return children, nil
+}
+
+// loadLeaf returns the leaf with the provided key and count without
+// reading its contents
+func (s *NodeStore[T]) loadLeaf(key Key, count int) Node[T] {
+	if n, ok := s.recall(key); ok && n.Children == nil && n.Count == count {
+		return n
+	}
+
+	n := Node[T]{ids: s.ids, Count: count}.fresh()
+	n.lazy = &lazyLeaf[T]{fetch: func() (T, error) {
+		var zero T
+		tag, c, rest, err := s.read(key)
+		switch {
+		case err != nil:
+			return zero, err
+		case tag != tagLeaf || c != count:
+			return zero, fmt.Errorf("trope: invalid count in blob %v", key)
+		}
+		return s.codec.UnmarshalLeaf(rest)
+	}}
+	n.store(s, key)
+	return s.remember(key, n)
+}
+
+// recall returns the node with the provided key if it is one of the
+// recently used nodes
+func (s *NodeStore[T]) recall(key Key) (Node[T], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.nodes[key]; ok {
+		s.recent.MoveToFront(e)
+		return e.Value.(storedNode[T]).node, true
+	}
+	return Node[T]{}, false
+}
+
+// remember adds n to the recently used nodes, dropping the least
+// recently used node if there are too many.  If the key is already
+// there (such as when it is loaded concurrently), the node already
+// there is returned instead of n.
+func (s *NodeStore[T]) remember(key Key, n Node[T]) Node[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.nodes[key]; ok {
+		s.recent.MoveToFront(e)
+		return e.Value.(storedNode[T]).node
+	}
+	s.nodes[key] = s.recent.PushFront(storedNode[T]{key, n})
+	if s.recent.Len() > recentNodes {
+		oldest := s.recent.Remove(s.recent.Back())
+		delete(s.nodes, oldest.(storedNode[T]).key)
+	}
+	return n
+}
+
+// lazyLeaf reads the contents of a leaf when first needed.  A failed
+// read is retried the next time.
+type lazyLeaf[T any] struct {
+	mu    sync.Mutex
+	fetch func() (T, error)
+	leaf  atomic.Pointer[T]
+}
+
+func (l *lazyLeaf[T]) load() (T, error) {
+	if v := l.leaf.Load(); v != nil {
+		return *v, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if v := l.leaf.Load(); v != nil {
+		return *v, nil
+	}
+	v, err := l.fetch()
+	if err != nil {
+		return v, err
+	}
+	l.leaf.Store(&v)
+	return v, nil
+}
+
+// Resolve returns n with Leaf filled in if n is a leaf loaded by a
+// NodeStore, reading it if needed.  It returns the error if the leaf
+// cannot be read.  Other nodes are returned as is.
+func (n Node[T]) Resolve() (Node[T], error) {
+	if n.lazy == nil {
+		return n, nil
+	}
+	v, err := n.lazy.load()
+	if err != nil {
+		return n, err
+	}
+	n.Leaf, n.lazy = v, nil
+	return n, nil
+}
+
+// value returns the Leaf of n, reading it first if n was loaded by a
+// NodeStore.  It panics if the leaf cannot be read.
+func (n Node[T]) value() T {
+	n, err := n.Resolve()
+	if err != nil {
+		panic(err)
+	}
+	return n.Leaf
+}
+
+// loaded is like Resolve but panics if the leaf cannot be read
+func (n Node[T]) loaded() Node[T] {
+	n.Leaf, n.lazy = n.value(), nil
+	return n
+}
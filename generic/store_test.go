@@ -0,0 +1,215 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"errors"
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type countingStore struct {
+	generic.Store
+	gets, puts int
+}
+
+func (s *countingStore) Get(key generic.Key) ([]byte, error) {
+	s.gets++
+	return s.Store.Get(key)
+}
+
+func (s *countingStore) Put(key generic.Key, data []byte) error {
+	s.puts++
+	return s.Store.Put(key, data)
+}
+
+func TestNodeStore(t *testing.T) {
+	fs, err := generic.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal("NewFileStore", err)
+	}
+
+	for _, store := range []generic.Store{generic.NewMemStore(), fs} {
+		rand.Seed(42)
+		counting := &countingStore{Store: store}
+		s := generic.NewNodeStore[text](counting, textCodec{})
+
+		n := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+		for kk := 0; kk < 200; kk++ {
+			n = n.Append(text(randomText(5)), 5)
+		}
+		versions := []generic.Node[text]{n}
+		for kk := 0; kk < 20; kk++ {
+			n = n.Splice(rand.Intn(n.Count), 1, generic.New(text("x"), 1))
+			versions = append(versions, n)
+		}
+
+		var keys []generic.Key
+		for kk, v := range versions {
+			puts := counting.puts
+			key, err := s.Save(v)
+			if err != nil {
+				t.Fatal("Save", err)
+			}
+			if kk > 0 && counting.puts-puts > 2*v.Stats().Depth+2 {
+				t.Fatal("Saved unchanged nodes", counting.puts-puts)
+			}
+			keys = append(keys, key)
+		}
+
+		// a fresh NodeStore loads from the blobs
+		s = generic.NewNodeStore[text](counting, textCodec{})
+		var loaded []generic.Node[text]
+		for kk, key := range keys {
+			gets := counting.gets
+			v, err := s.Load(key)
+			if err != nil {
+				t.Fatal("Load", err)
+			}
+			if toString(v) != toString(versions[kk]) || v.Validate() != nil {
+				t.Fatal("Diverged", kk)
+			}
+			if kk > 0 && counting.gets-gets > 2*v.Stats().Depth+2 {
+				t.Fatal("Loaded unchanged nodes", counting.gets-gets)
+			}
+			loaded = append(loaded, v)
+		}
+
+		if edits := generic.Diff(loaded[0], loaded[1]); len(edits) != 1 {
+			t.Fatal("Unexpected edits", edits)
+		}
+		if key, err := s.Save(loaded[5]); err != nil || key != keys[5] {
+			t.Fatal("Unexpected key", key, err)
+		}
+		if _, err := s.Load(generic.Key{}); !errors.Is(err, generic.ErrNotFound) {
+			t.Fatal("Unexpected error", err)
+		}
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	store, err := generic.NewFileStore(dir)
+	if err != nil {
+		t.Fatal("NewFileStore", err)
+	}
+	key, err := generic.NewNodeStore[text](store, textCodec{}).Save(generic.New(text("hello"), 5))
+	if err != nil {
+		t.Fatal("Save", err)
+	}
+
+	path := filepath.Join(dir, key.String()[:2], key.String()[2:])
+	if err := os.WriteFile(path, []byte("junk"), 0o644); err != nil {
+		t.Fatal("WriteFile", err)
+	}
+	if _, err := generic.NewNodeStore[text](store, textCodec{}).Load(key); err == nil {
+		t.Fatal("Unexpected success")
+	}
+
+	if k, err := generic.ParseKey(key.String()); err != nil || k != key {
+		t.Fatal("ParseKey", k, err)
+	}
+	if _, err := generic.ParseKey("abc"); err == nil {
+		t.Fatal("Unexpected success")
+	}
+}
+
+func TestNodeStoreIDs(t *testing.T) {
+	mem := generic.NewMemStore()
+	key, err := generic.NewNodeStore[text](mem, textCodec{}).Save(generic.New(text("hello"), 5).Append(" world", 6))
+	if err != nil {
+		t.Fatal("Save", err)
+	}
+
+	ids := generic.NewIDSource(generic.RandomIDs())
+	n, err := generic.NewNodeStoreWithIDs[text](mem, textCodec{}, ids).Load(key)
+	if err != nil || toString(n) != "hello world" {
+		t.Fatal("Load", toString(n), err)
+	}
+	if n.Forest().IDSource() != ids || n.UID() != ids.UID(n.ID) {
+		t.Fatal("Unexpected ID source")
+	}
+}
+
+func TestNodeStoreLazy(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := generic.NewFileStore(dir)
+	if err != nil {
+		t.Fatal("NewFileStore", err)
+	}
+	counting := &countingStore{Store: fs}
+
+	n := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < 5000; kk++ {
+		n = n.Append(text(randomText(5)), 5)
+	}
+	key, err := generic.NewNodeStore[text](counting, textCodec{}).Save(n)
+	if err != nil {
+		t.Fatal("Save", err)
+	}
+	leaf, _, _ := n.Locate(0)
+	leafKey, err := generic.NewNodeStore[text](counting, textCodec{}).Save(leaf)
+	if err != nil {
+		t.Fatal("Save", err)
+	}
+
+	// Load reads the internal nodes and At reads just the one leaf
+	s := generic.NewNodeStore[text](counting, textCodec{})
+	gets := counting.gets
+	v, err := s.Load(key)
+	if err != nil || v.Count != n.Count || v.Children == nil || counting.gets-gets != n.Stats().Internal {
+		t.Fatal("Load", v.Count, counting.gets-gets, err)
+	}
+	if v.Stats() != n.Stats() {
+		t.Fatal("Unexpected shape", v.Stats(), n.Stats())
+	}
+	gets = counting.gets
+	expected, _ := n.At(12345)
+	if got, _ := v.At(12345); got != expected || counting.gets-gets != 1 {
+		t.Fatal("Unexpected leaf", got, expected, counting.gets-gets)
+	}
+	if toString(v) != toString(n) {
+		t.Fatal("Diverged")
+	}
+
+	// the nodes kept are bounded: the first leaf loaded has been
+	// pushed out by the rest, so loading it again reads it again
+	gets = counting.gets
+	if l, err := s.Load(leafKey); err != nil || toString(l) != toString(leaf) || counting.gets-gets != 1 {
+		t.Fatal("Unexpected reuse", counting.gets-gets, err)
+	}
+
+	// missing leaves are reported when they are first used
+	last := n.Count - 1
+	leaf, _, _ = n.Locate(last)
+	leafKey, err = generic.NewNodeStore[text](counting, textCodec{}).Save(leaf)
+	if err != nil {
+		t.Fatal("Save", err)
+	}
+	if err := os.Remove(filepath.Join(dir, leafKey.String()[:2], leafKey.String()[2:])); err != nil {
+		t.Fatal("Remove", err)
+	}
+	v, err = generic.NewNodeStore[text](counting, textCodec{}).Load(key)
+	if err != nil {
+		t.Fatal("Load", err)
+	}
+	missing := v
+	for missing.Children != nil {
+		missing = missing.Children[len(missing.Children)-1]
+	}
+	if _, err := missing.Resolve(); !errors.Is(err, generic.ErrNotFound) {
+		t.Fatal("Unexpected error", err)
+	}
+	if err := v.Validate(); !errors.Is(err, generic.ErrNotFound) {
+		t.Fatal("Unexpected error", err)
+	}
+	if got, _ := v.At(0); got != text(toString(n)[:5]) {
+		t.Fatal("Unexpected leaf", got)
+	}
+	mustPanic(t, func() { v.At(last) })
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+// Summary computes an aggregate of type S (such as a count of lines
+// or a width) for nodes.  Leaves are summarized by the leaf function
+// and the summaries of the children of an internal node are joined
+// with the combine function.  Combine must be associative and the
+// zero value of S must be the summary of an empty node (so combining
+// with it changes nothing).
+//
+// The summary of a node is stored on the node once computed.  Nodes
+// are immutable and edits (by Splice, Slice, Flatten and so on) only
+// create new nodes along the path of the edit, so summarizing an
+// edited node only summarizes those.  A Summary is safe for
+// concurrent use.
+//
+// A node keeps the summary stored for every Summary it was used with
+// and never drops it, so each Summary adds to the memory of a node.
+// A Summary is meant to be long-lived, such as a package-level
+// variable created once per kind of summary: creating one per
+// request or per call grows the nodes without bound.
+type Summary[T, S any] struct {
+	leaf    func(v T, count int) S
+	combine func(a, b S) S
+}
+
+// NewSummary creates a Summary with the provided functions.  Create
+// it once and reuse it (see Summary).
+func NewSummary[T, S any](leaf func(v T, count int) S, combine func(a, b S) S) *Summary[T, S] {
+	return &Summary[T, S]{leaf: leaf, combine: combine}
+}
+
+// Of returns the summary of n and stores it on n and the descendants
+// of n for this Summary.  It panics if a leaf loaded by a NodeStore
+// cannot be read.
+func (s *Summary[T, S]) Of(n Node[T]) S {
+	if n.Count == 0 {
+		var zero S
+		return zero
+	}
+
+	if v, ok := n.load(s); ok {
+		return v.(S)
+	}
+
+	var result S
+	if n.Children == nil {
+		result = s.leaf(n.value(), n.Count)
+	} else {
+		for _, child := range n.Children {
+			result = s.combine(result, s.Of(child))
+		}
+	}
+
+	n.store(s, result)
+	return result
+}
+
+// Seek descends n by a summarized dimension.  The metric extracts the
+// dimension from a summary and must not decrease as summaries are
+// combined.  Seek returns the first leaf whose summary takes the
+// metric past the target, the offset of that leaf and the summary of
+// everything before it.  If the metric of n does not exceed the
+// target, Seek returns an empty leaf at the end of n and the summary
+// of all of n.
+func (s *Summary[T, S]) Seek(n Node[T], metric func(S) int, target int) (leaf Node[T], offset int, before S) {
+	if metric(s.Of(n)) <= target {
+		return Node[T]{}, n.Count, s.Of(n)
+	}
+
+	for n.Children != nil {
+		for _, child := range n.Children {
+			next := s.combine(before, s.Of(child))
+			if metric(next) > target {
+				n = child
+				break
+			}
+			before, offset = next, offset+child.Count
+		}
+	}
+	return n, offset, before
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+type lineStats struct {
+	lines, bytes int
+}
+
+func TestSummary(t *testing.T) {
+	rand.Seed(42)
+	calls := 0
+	lines := generic.NewSummary(func(v text, count int) int {
+		calls++
+		return strings.Count(string(v), "\n")
+	}, func(a, b int) int { return a + b })
+
+	n := generic.NewWithOptions(text(""), 0, generic.Options{MaxFanout: 4})
+	for kk := 0; kk < 200; kk++ {
+		n = n.Append(text(randomText(5)+"\n"), 6)
+	}
+
+	lines.Of(n)
+	for kk := 0; kk < 200; kk++ {
+		offset := rand.Intn(n.Count + 1)
+		count := rand.Intn(n.Count-offset+1) / 20
+		insert := generic.New(text(strings.Repeat("\n", rand.Intn(3))+"x"), 0)
+		insert.Count = len(insert.Leaf)
+		switch kk % 3 {
+		case 0:
+			n = n.Splice(offset, count, insert)
+		case 1:
+			n = n.Slice(0, n.Count-count)
+		default:
+			n = n.Flatten(0)
+		}
+
+		calls = 0
+		str := toString(n)
+		if got := lines.Of(n); got != strings.Count(str, "\n") {
+			t.Fatal("Unexpected summary", got, strings.Count(str, "\n"))
+		}
+		if kk%3 == 0 && calls > 4 {
+			t.Fatal("Summarized unchanged leaves", calls)
+		}
+
+		target := rand.Intn(strings.Count(str, "\n") + 1)
+		leaf, offset, before := lines.Seek(n, func(s int) int { return s }, target)
+		if offset+leaf.Count > n.Count || before != strings.Count(str[:offset], "\n") {
+			t.Fatal("Unexpected seek", offset, before)
+		}
+		if before <= target && leaf.Count > 0 && before+strings.Count(string(leaf.Leaf), "\n") <= target {
+			t.Fatal("Seek stopped early", offset, before, target)
+		}
+	}
+
+	leaf, offset, before := lines.Seek(n, func(s int) int { return s }, lines.Of(n))
+	if leaf.Count != 0 || offset != n.Count || before != lines.Of(n) {
+		t.Fatal("Unexpected seek past the end", offset, before)
+	}
+}
+
+func TestSummaryStruct(t *testing.T) {
+	stats := generic.NewSummary(func(v text, count int) lineStats {
+		return lineStats{strings.Count(string(v), "\n"), count}
+	}, func(a, b lineStats) lineStats {
+		return lineStats{a.lines + b.lines, a.bytes + b.bytes}
+	})
+
+	n := generic.New(text("ab\ncdef"), 7).Append(text("gh\ni"), 4)
+	if s := stats.Of(n); s.lines != 2 || s.bytes != 11 {
+		t.Fatal("Unexpected summary", s)
+	}
+	leaf, offset, before := stats.Seek(n, func(s lineStats) int { return s.lines }, 1)
+	if leaf.Leaf != "gh\ni" || offset != 7 || before.lines != 1 {
+		t.Fatal("Unexpected seek", leaf.Leaf, offset, before)
+	}
+}
+
+func TestSummaryLarge(t *testing.T) {
+	rand.Seed(42)
+	calls := 0
+	lines := generic.NewSummary(func(v text, count int) int {
+		calls++
+		return strings.Count(string(v), "\n")
+	}, func(a, b int) int { return a + b })
+
+	n := generic.New(text(""), 0)
+	for kk := 0; kk < 80000; kk++ {
+		n = n.Append("a\n", 2)
+	}
+	lines.Of(n)
+
+	for kk := 0; kk < 100; kk++ {
+		offset := 2 * rand.Intn(n.Count/2)
+		n = n.Splice(offset, 1, generic.New(text("b"), 1))
+
+		calls = 0
+		target := rand.Intn(80000)
+		_, offset, before := lines.Seek(n, func(s int) int { return s }, target)
+		if before != target || offset != 2*target || calls > 3 {
+			t.Fatal("Unexpected seek", offset, before, calls)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Package generic implements the type-parameterized trope.
+//
+// Node[T] and Hybrid[T] are the same rope-like structures as
+// trope.Node and trope.Hybrid (which are simply the interface{}
+// instantiations of these) but the leaf values are of type T all the
+// way through, so callers do not need type assertions.
+package generic
+
+// Slicer is an optional interface to be implemented by the leaf-node
+// values.  If the leaf-node value are all single-item arrays, this is
+// not needed at all.
+type Slicer[T any] interface {
+	Slice(offset, count int) T
+}
+
+// Node is the immutable node in the tree representing the
+// collection. Use New() to create a new node.  The ID is guaranteed
+// to be unique for a  specific tree -- edits will cause those nodes
+// that changed (typically along the path of the edit) to get a new
+// ID.
+//
+// Each node created by New has its own ID space.  Nodes spliced in
+// from a different ID space are assigned new IDs, so use a Forest to
+// create nodes that are meant to be spliced into each other.
+//
+// Nodes can be read and edited concurrently from multiple goroutines
+// without any locking as edits always produce new nodes.
+//
+// The fields are exported for reading: a node must not be changed in
+// place.  The hash and summaries of a node are stored with it once
+// computed and copies with the same ID reuse them, so a node whose
+// Leaf is replaced in place may still report the old ones.  Only the
+// changes to Count or to whether the node is a leaf are detected.
+//
+// If Children is nil, the node simply holds the underlying leaf
+// element(s). Count is still valid and specifies the number of
+// elements.
+//
+// The leaves loaded by a NodeStore read their Leaf on first use, so
+// Leaf holds the zero value until then (see Resolve).  The methods
+// which need the elements of such a leaf panic if it cannot be read.
+type Node[T any] struct {
+	ids      *IDSource
+	opts     *Options
+	spare    *spare[T]
+	cache    *nodeCache
+	lazy     *lazyLeaf[T]
+	ID       int
+	Children []Node[T]
+	Leaf     T
+	Count    int
+}
+
+// New creates a new node populated  with the initial elements of
+// specified count. The provided initial elements are stored as the
+// Leaf value.
+func New[T any](initial T, count int) Node[T] {
+	return Node[T]{ids: &IDSource{}, Leaf: initial, Count: count}.withCache()
+}
+
+// NewWithOptions is like New but the tree shape is controlled by the
+// provided options.  All nodes derived from the result (via Slice,
+// Splice etc) use the same options.
+func NewWithOptions[T any](initial T, count int, opts Options) Node[T] {
+	return New(initial, count).WithOptions(opts)
+}
+
+// Size returns the number of elements, same as Count.  This matches
+// Hybrid.Size.
+func (n Node[T]) Size() int {
+	return n.Count
+}
+
+// ForEach recursively traverses the node and its children calling the
+// provided function on all the Leaf values.  It panics if a leaf
+// loaded by a NodeStore cannot be read.
+func (n Node[T]) ForEach(fn func(v T, count int)) {
+	n.forEach(func(leaf Node[T]) {
+		fn(leaf.value(), leaf.Count)
+	})
+}
+
+func (n Node[T]) forEach(fn func(n Node[T])) {
+	if n.Count == 0 {
+		return
+	}
+
+	if n.Children == nil {
+		fn(n)
+		return
+	}
+
+	for _, child := range n.Children {
+		child.forEach(fn)
+	}
+}
+
+// At returns the leaf value holding the element at the provided
+// index along with the offset of the element within that leaf.  It
+// panics if the leaf was loaded by a NodeStore and cannot be read.
+func (n Node[T]) At(index int) (leaf T, offset int) {
+	n.checkIndex(index)
+	for n.Children != nil {
+		var kk int
+		kk, index = n.child(index)
+		n = n.Children[kk]
+	}
+	return n.value(), index
+}
+
+// Locate is like At but returns the leaf Node itself (with Leaf
+// filled in).  The path holds the index into Children at each level,
+// starting from n.  Like At, it panics if the leaf cannot be read.
+func (n Node[T]) Locate(index int) (leaf Node[T], offset int, path []int) {
+	n.checkIndex(index)
+	for n.Children != nil {
+		var kk int
+		kk, index = n.child(index)
+		path = append(path, kk)
+		n = n.Children[kk]
+	}
+	return n.loaded(), index, path
+}
+
+func (n Node[T]) checkIndex(index int) {
+	if index < 0 || index >= n.Count {
+		panic("Unexpected index")
+	}
+}
+
+// child returns the position of the child holding the element at
+// index and the offset of the element within that child.
+func (n Node[T]) child(index int) (int, int) {
+	kk := 0
+	for index >= n.Children[kk].Count {
+		index -= n.Children[kk].Count
+		kk++
+	}
+	return kk, index
+}
+
+// Flatten constructs a 2-level list. The leaf nodes are all
+// aggregated into the first level in groups of the specified chunk
+// size and these are all then aggregated into the root node.
+//
+// Note that the root node won't honor the chunk size.  A chunk size
+// of zero or less uses the MaxFanout option.  If the LeafSize option
+// is set, the leaf nodes are first resized towards that size.
+// Balanced nodes are simply rebalanced instead.  Resizing leaves
+// reads them, so it panics if a leaf loaded by a NodeStore cannot be
+// read.
+func (n Node[T]) Flatten(chunkSize int) Node[T] {
+	opts := n.Options()
+	if opts.Balanced {
+		return n.rebalance(opts.MaxFanout)
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = opts.MaxFanout
+	}
+
+	children := []Node[T](nil)
+	leafs := []Node[T](nil)
+	n.forEachResized(func(leaf Node[T]) {
+		leafs = append(leafs, leaf)
+		if len(leafs) == chunkSize {
+			children = append(children, n.parent(leafs))
+			leafs = nil
+		}
+	})
+	if leafs != nil {
+		children = append(children, n.parent(leafs))
+	}
+	n = n.fresh()
+	n.Children = children
+	return n
+}
+
+// Slice returns a Node which references only the elements between
+// offset and offset+count. If this involves slicing leaf nodes, it
+// will look for the leaf node elements to implement the Slicer
+// interface.  The leaves sliced are read if they were loaded by a
+// NodeStore, panicking if that fails.
+func (n Node[T]) Slice(offset, count int) Node[T] {
+	if checkRange(offset, count, n.Count) != nil {
+		panic("Unexpected offset, count")
+	}
+
+	if offset == 0 && count == n.Count {
+		return n
+	}
+
+	if count == 0 {
+		return n.empty()
+	}
+
+	if n.Children == nil {
+		return n.sliceLeaf(offset, count)
+	}
+
+	if n.Options().Balanced {
+		return n.sliceBalanced(offset, count)
+	}
+
+	seen := 0
+	children := []Node[T]{}
+	for kk := 0; kk < len(n.Children) && seen < offset+count; kk++ {
+		child, start, end := n.Children[kk], seen, seen+n.Children[kk].Count
+		if offset > start {
+			start = offset
+		}
+		if offset+count < end {
+			end = offset + count
+		}
+		if start < end {
+			children = append(children, child.Slice(start-seen, end-start))
+		}
+		seen = end
+	}
+
+	n = n.fresh()
+	n.Children = children
+	n.Count = count
+	return n
+}
+
+// Splice removes the elements at the provided offset and replaces
+// them with the provided replacement.
+//
+// If the FlattenDepth option is set and the edit leaves the path to
+// the offset deeper than that, the result is flattened.
+//
+// Like Slice, Splice panics if a leaf it has to slice or merge was
+// loaded by a NodeStore and cannot be read.  So do Insert, Delete,
+// Append, Prepend and Concat which are all built on Splice.
+func (n Node[T]) Splice(offset, count int, replacement Node[T]) Node[T] {
+	if checkRange(offset, count, n.Count) != nil {
+		panic("Unexpected offset, count")
+	}
+
+	replacement = n.adopt(replacement)
+	opts := n.Options()
+	if opts.Balanced {
+		return n.spliceBalanced(offset, count, replacement)
+	}
+
+	result := n.splice(offset, count, replacement)
+	if opts.FlattenDepth > 0 && result.depthAt(offset) > opts.FlattenDepth {
+		return result.Flatten(0)
+	}
+	return result
+}
+
+func (n Node[T]) splice(offset, count int, replacement Node[T]) Node[T] {
+	if offset == 0 && count == n.Count {
+		if replacement.Count == 0 {
+			return n.empty()
+		}
+		replacement.ids, replacement.opts = n.ids, n.opts
+		return replacement.fresh()
+	}
+
+	if offset == n.Count && count == 0 {
+		return n.join(replacement)
+	}
+
+	// if it affects a sub-node only, then optimize for it
+	seen := 0
+	for kk := 0; kk < len(n.Children) && seen <= offset; kk++ {
+		child := n.Children[kk]
+		if seen+child.Count >= offset+count {
+			child = child.splice(offset-seen, count, replacement)
+			if child.Count == 0 {
+				n.Children = append(n.Children[:kk:kk], n.Children[kk+1:]...)
+			} else {
+				n.Children = append([]Node[T](nil), n.Children...)
+				n.Children[kk] = child
+			}
+			n = n.fresh()
+			n.Count += replacement.Count - count
+			return n
+		}
+		seen += child.Count
+	}
+
+	// slow path
+	children := n.Children
+	if len(children) > 0 {
+		first := children[0]
+		last := children[len(children)-1]
+		if offset >= first.Count || offset+count <= n.Count-last.Count {
+			return n.spliceChildren(offset, count, replacement)
+		}
+	}
+
+	// slower path
+	right := n.Slice(offset+count, n.Count-offset-count)
+	return n.Slice(0, offset).join(replacement).join(right)
+}
+
+func (n Node[T]) spliceChildren(offset, count int, replacement Node[T]) Node[T] {
+	left, right, mid := 0, 0, 0
+	leftCount, rightCount, midCount := 0, 0, 0
+	seen := 0
+	for _, ch := range n.Children {
+		switch {
+		case seen+ch.Count <= offset:
+			left++
+			leftCount += ch.Count
+		case seen >= offset+count:
+			right++
+			rightCount += ch.Count
+		default:
+			mid++
+			midCount += ch.Count
+		}
+		seen += ch.Count
+	}
+	innerLeft := n.Children[left].Slice(0, offset-leftCount)
+	r := n.Children[left+mid-1]
+	offsetr := offset + count - (n.Count - rightCount - r.Count)
+	countr := r.Count - offsetr
+	innerRight := r.Slice(offsetr, countr)
+	inner := innerLeft.join(replacement).join(innerRight)
+	result := n.fresh()
+	result.Count = n.Count - count + replacement.Count
+	result.Children = n.Children[:left:left]
+	switch {
+	case inner.Count == 0:
+	case len(inner.Children) > 0 && len(n.Children)-mid+len(inner.Children) <= n.Options().MaxFanout:
+		result.Children = append(result.Children, inner.Children...)
+	default:
+		result.Children = append(result.Children, inner)
+	}
+	result.Children = append(result.Children, n.Children[left+mid:]...)
+	return result
+}
+
+func (n Node[T]) join(o Node[T]) Node[T] {
+	opts := n.Options()
+	result := n
+	switch {
+	case n.Count == 0:
+		result = o
+		result.ids, result.opts = n.ids, n.opts
+	case o.Count == 0:
+	default:
+		if n.Children != nil && o.Children == nil {
+			last := len(n.Children) - 1
+			if leaf, ok := n.mergeLeaves(n.Children[last], o, opts.MinLeafSize); ok {
+				result.Children = append(append([]Node[T](nil), n.Children[:last]...), leaf)
+				break
+			}
+		} else if leaf, ok := n.mergeLeaves(n, o, opts.MinLeafSize); ok {
+			return leaf
+		}
+
+		var zero T
+		left, right := n.items(), o.items()
+		result.Leaf, result.lazy = zero, nil
+		switch {
+		case len(left)+len(right) <= opts.MaxFanout:
+			result.Children = append(append([]Node[T](nil), left...), right...)
+		case len(right) < opts.MaxFanout:
+			result.Children = append([]Node[T]{n}, right...)
+		case len(left) < opts.MaxFanout:
+			result.Children = append(append([]Node[T](nil), left...), o)
+		default:
+			result.Children = []Node[T]{n, o}
+		}
+	}
+	result.Count = n.Count + o.Count
+	return result.fresh()
+}
+
+// items returns the children of the node or the node itself if it is
+// a leaf
+func (n Node[T]) items() []Node[T] {
+	if n.Children == nil {
+		return []Node[T]{n}
+	}
+	return n.Children
+}
+
+func (n Node[T]) sliceLeaf(offset, count int) Node[T] {
+	n = n.fresh()
+	n.Leaf, n.lazy = n.leafSlice(offset, count), nil
+	n.Count = count
+	return n
+}
+
+func (n Node[T]) leafSlice(offset, count int) T {
+	return any(n.value()).(Slicer[T]).Slice(offset, count)
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"github.com/perdata/trope/generic"
+	"testing"
+)
+
+func TestNode(t *testing.T) {
+	hello := generic.New(text("hello"), 5)
+	if x := toString(hello.Slice(1, 3)); x != "ell" {
+		t.Fatal("slice", x)
+	}
+
+	jello := hello.Splice(0, 1, generic.New(text("j"), 1))
+	if x := toString(jello); x != "jello" {
+		t.Fatal("jello", x)
+	}
+
+	jimbo := jello.Splice(1, 3, generic.New(text("imb"), 3))
+	if x := toString(jimbo); x != "jimbo" {
+		t.Fatal("jimbo", x)
+	}
+
+	jinova := jimbo.Splice(2, 2, generic.New(text("n"), 1)).Splice(4, 0, generic.New(text("va"), 2))
+	if x := toString(jinova); x != "jinova" {
+		t.Fatal("jinova", x)
+	}
+
+	if x := toString(jinova.Flatten(2)); x != "jinova" {
+		t.Fatal("flatten", x)
+	}
+}
+
+func TestUnsliceableLeaf(t *testing.T) {
+	mustPanic(t, func() { generic.New(42, 2).Slice(0, 1) })
+}
+
+func TestAt(t *testing.T) {
+	hello := generic.New(text("hello"), 5)
+	n := hello.Splice(5, 0, generic.New(text(" world"), 6)).Splice(0, 0, generic.New(text(">"), 1))
+	str := ">hello world"
+	for kk := range str {
+		leaf, offset := n.At(kk)
+		if leaf[offset] != str[kk] {
+			t.Fatal("At", kk, leaf, offset)
+		}
+
+		node, offset, path := n.Locate(kk)
+		if node.Leaf[offset] != str[kk] {
+			t.Fatal("Locate", kk, node.Leaf, offset)
+		}
+
+		walk := n
+		for _, idx := range path {
+			walk = walk.Children[idx]
+		}
+		if walk.ID != node.ID {
+			t.Fatal("Locate path", kk, path)
+		}
+	}
+
+	mustPanic(t, func() { n.At(-1) })
+	mustPanic(t, func() { n.Locate(len(str)) })
+}
+
+func mustPanic(t *testing.T, fn func()) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Failed to panic")
+		}
+	}()
+	fn()
+}
+
+func toString(n generic.Node[text]) string {
+	result := ""
+	n.ForEach(func(leaf text, count int) {
+		result += string(leaf)
+	})
+	return result
+}
+
+type text string
+
+func (s text) Slice(offset, count int) text {
+	return s[offset : offset+count]
+}
+
+func (s text) Splice(offset, count int, replacement text) text {
+	return s[:offset] + replacement + s[offset+count:]
+}
+
+func (s text) Index(offset int) byte {
+	return s[offset]
+}
+
+func (s text) Len() int {
+	return len(s)
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Lener is an optional interface to be implemented by the leaf-node
+// values.  Validate uses it to confirm that the leaf value actually
+// holds Count elements.
+type Lener interface {
+	Len() int
+}
+
+// ValidationError is the error returned by Validate
+type ValidationError struct {
+	// Path holds the index into Children at each level from the
+	// root to the bad node.
+	Path   []int
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("trope: invalid node at %v: %s", e.Path, e.Reason)
+}
+
+// Validate checks the invariants of the tree: the Count of every
+// internal node is the sum of the counts of its children, there are
+// no empty children, IDs are unique within the tree and leaves that
+// implement Lener have exactly Count elements.
+//
+// The same node can appear more than once in a tree (such as when a
+// node is spliced into itself) and this is not treated as an error.
+// Leaves loaded by a NodeStore are read and the error of any leaf
+// that cannot be read is returned.
+func (n Node[T]) Validate() error {
+	return n.validate(nil, map[int]Node[T]{})
+}
+
+func (n Node[T]) validate(path []int, seen map[int]Node[T]) error {
+	if other, ok := seen[n.ID]; ok {
+		if !n.same(other) {
+			return n.invalid(path, "duplicate ID %d", n.ID)
+		}
+		return nil
+	}
+	seen[n.ID] = n
+
+	if n.Children == nil {
+		n, err := n.Resolve()
+		if err != nil {
+			return err
+		}
+		if l, ok := any(n.Leaf).(Lener); ok && l.Len() != n.Count {
+			return n.invalid(path, "leaf length %d != count %d", l.Len(), n.Count)
+		}
+		return nil
+	}
+
+	count := 0
+	for kk, child := range n.Children {
+		p := append(path[:len(path):len(path)], kk)
+		if child.Count == 0 {
+			return n.invalid(p, "empty child")
+		}
+		if err := child.validate(p, seen); err != nil {
+			return err
+		}
+		count += child.Count
+	}
+	if count != n.Count {
+		return n.invalid(path, "count %d != sum of children %d", n.Count, count)
+	}
+	return nil
+}
+
+func (n Node[T]) invalid(path []int, format string, args ...interface{}) error {
+	return &ValidationError{path, fmt.Sprintf(format, args...)}
+}
+
+// same checks if the two nodes are likely the same node (as opposed
+// to different nodes with the same ID).  Internal nodes are compared
+// by their children and leaves by identity: leaves that are not
+// comparable (such as Bytes) are the same if they share their data.
+func (n Node[T]) same(o Node[T]) bool {
+	switch {
+	case n.ID != o.ID || n.Count != o.Count || len(n.Children) != len(o.Children):
+		return false
+	case (n.Children == nil) != (o.Children == nil):
+		return false
+	case n.Children != nil:
+		if len(n.Children) == 0 || &n.Children[0] == &o.Children[0] {
+			return true
+		}
+		for kk := range n.Children {
+			if !n.Children[kk].same(o.Children[kk]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if n.lazy != nil && n.lazy == o.lazy {
+		return true
+	}
+	a, b := reflect.ValueOf(any(n.value())), reflect.ValueOf(any(o.value()))
+	switch {
+	case !a.IsValid() || !b.IsValid():
+		return a.IsValid() == b.IsValid()
+	case a.Type() != b.Type():
+		return false
+	case a.Comparable():
+		return a.Equal(b)
+	}
+
+	switch a.Kind() {
+	case reflect.Slice:
+		return a.Pointer() == b.Pointer() && a.Len() == b.Len()
+	case reflect.Map, reflect.Func:
+		return a.Pointer() == b.Pointer()
+	}
+	return true
+}
+
+// Validate works like Node.Validate.  In Raw mode, it checks the Raw
+// value if it implements Lener.
+func (h Hybrid[T]) Validate() error {
+	if h.Node.Count > 0 {
+		return h.Node.Validate()
+	}
+	if l, ok := h.Raw.(Lener); ok && l.Len() != h.Count {
+		return &ValidationError{nil, fmt.Sprintf("raw length %d != count %d", l.Len(), h.Count)}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package generic_test
+
+import (
+	"errors"
+	"github.com/perdata/trope/generic"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	hello := generic.New(text("hello world"), 11)
+	n := hello.Splice(5, 1, generic.New(text("_"), 1))
+	if err := n.Validate(); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	for offset := 0; offset < n.Count; offset++ {
+		deleted := n.Splice(offset, 1, n.Slice(0, 0))
+		if err := deleted.Validate(); err != nil {
+			t.Fatal("Unexpected error after delete", offset, err)
+		}
+	}
+
+	doubled := n.Splice(0, 0, n)
+	if err := doubled.Validate(); err != nil {
+		t.Fatal("Unexpected error for shared nodes", err)
+	}
+
+	b := generic.FromBytes([]byte("hello")).Append(generic.Bytes(" world"), 6)
+	if err := b.Splice(0, 0, b).Validate(); err != nil {
+		t.Fatal("Unexpected error for shared Bytes nodes", err)
+	}
+	s := generic.FromSlice([]int{1, 2, 3}).Append(generic.Slice[int]{4}, 1)
+	if err := s.Splice(2, 0, s).Validate(); err != nil {
+		t.Fatal("Unexpected error for shared Slice nodes", err)
+	}
+	dup := b
+	dup.Children = append([]generic.Node[generic.Bytes](nil), b.Children...)
+	dup.Children[1] = generic.Node[generic.Bytes]{ID: b.Children[0].ID, Leaf: generic.Bytes("hello "), Count: 6}
+	if err := dup.Validate(); err == nil {
+		t.Fatal("Expected duplicate ID error for Bytes")
+	}
+
+	bad := n
+	bad.Children = append([]generic.Node[text](nil), n.Children...)
+	bad.Count++
+	expectInvalid(t, bad, []int{}, "count 12 != sum of children 11")
+	bad.Count--
+
+	bad.Children[1] = generic.Node[text]{ID: 42, Children: []generic.Node[text]{}}
+	expectInvalid(t, bad, []int{1}, "empty child")
+
+	bad.Children[1] = generic.Node[text]{ID: 42, Leaf: "__", Count: 1}
+	expectInvalid(t, bad, []int{1}, "leaf length 2 != count 1")
+
+	bad.Children[1] = generic.Node[text]{ID: bad.Children[0].ID, Leaf: "_", Count: 1}
+	expectInvalid(t, bad, []int{1}, "duplicate ID")
+
+	if err := hybridRaw("hello").Validate(); err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	h := hybridRaw("hello")
+	h.Count = 4
+	if err := h.Validate(); err == nil {
+		t.Fatal("Expected hybrid error")
+	}
+}
+
+func expectInvalid(t *testing.T, n generic.Node[text], path []int, reason string) {
+	t.Helper()
+	var verr *generic.ValidationError
+	if err := n.Validate(); !errors.As(err, &verr) {
+		t.Fatal("Expected validation error", err)
+	}
+	if len(verr.Path) != len(path) || len(path) > 0 && !reflect.DeepEqual(verr.Path, path) {
+		t.Fatal("Unexpected path", verr.Path, verr)
+	}
+	if len(verr.Reason) < len(reason) || verr.Reason[:len(reason)] != reason {
+		t.Fatal("Unexpected reason", verr.Reason)
+	}
+}
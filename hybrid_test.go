@@ -10,15 +10,15 @@ import (
 )
 
 func hybridNode(str string) trope.Hybrid {
-	return trope.Hybrid{100, 10, Slicer(""), 0, trope.New(Slicer(str), len(str))}
+	return trope.Hybrid{HighMark: 100, LowMark: 10, Raw: Slicer(""), Count: 0, Node: trope.New(Slicer(str), len(str))}
 }
 
 func hybridRaw(str string) trope.Hybrid {
-	return trope.Hybrid{100, 10, Slicer(str), len(str), trope.New(nil, 0)}
+	return trope.Hybrid{HighMark: 100, LowMark: 10, Raw: Slicer(str), Count: len(str), Node: trope.New(nil, 0)}
 }
 
 func TestHybridSwitcheroo(t *testing.T) {
-	zero := trope.Hybrid{10, 5, Slicer(""), 0, trope.New(nil, 0)}
+	zero := trope.Hybrid{HighMark: 10, LowMark: 5, Raw: Slicer(""), Count: 0, Node: trope.New(nil, 0)}
 	hw := zero.Splice(0, 0, hybridRaw("hello world"))
 	if hw.Count != 0 && hw.Node.Count != 11 {
 		t.Fatal("Failed to switch to larger size yo")
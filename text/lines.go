@@ -0,0 +1,116 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package text
+
+import "iter"
+
+// LineCount returns the number of lines.  Lines are separated by
+// "\n", "\r\n" or a lone "\r", so an empty text has one line and a
+// text ending with a line break has an empty last line.
+func (t Text) LineCount() int {
+	return summary.Of(t.node).lines + 1
+}
+
+// LineStart returns the byte offset of the start of the line
+func (t Text) LineStart(line int) int {
+	t.checkLine(line)
+	if line == 0 {
+		return 0
+	}
+
+	// find the line break ending the previous line
+	leaf, offset, before := summary.Seek(t.node, metrics.lineCount, line-1)
+	breaks, prev := line-before.lines, before.last
+	for kk := 0; ; kk++ {
+		c := leaf.Leaf[kk]
+		if c == '\n' && prev == '\r' {
+			prev = c
+			continue
+		}
+		prev = c
+		if c != '\n' && c != '\r' {
+			continue
+		}
+		if breaks--; breaks == 0 {
+			end := offset + kk + 1
+			if c == '\r' && end < t.Len() && t.prefix(end, 1) == "\n" {
+				end++
+			}
+			return end
+		}
+	}
+}
+
+// LineEnd returns the byte offset of the end of the line, not
+// including the line break
+func (t Text) LineEnd(line int) int {
+	t.checkLine(line)
+	if line == t.LineCount()-1 {
+		return t.Len()
+	}
+	end := t.LineStart(line + 1)
+	if end >= 2 && t.prefix(end-2, 2) == "\r\n" {
+		return end - 2
+	}
+	return end - 1
+}
+
+// Line returns the contents of the line without the line break
+func (t Text) Line(line int) Text {
+	start := t.LineStart(line)
+	return t.Slice(start, t.LineEnd(line)-start)
+}
+
+// Lines returns an iterator over the lines (see Line) along with
+// their line numbers
+func (t Text) Lines() iter.Seq2[int, Text] {
+	return func(yield func(int, Text) bool) {
+		for line := range t.LineCount() {
+			if !yield(line, t.Line(line)) {
+				return
+			}
+		}
+	}
+}
+
+// OffsetToLineCol converts a byte offset to a line and a column.  The
+// column is the byte offset within the line.  An offset between the
+// "\r" and "\n" of a line break is at the end of the line.
+func (t Text) OffsetToLineCol(offset int) (line, col int) {
+	t.checkRange(offset, 0)
+	leaf, start, before := summary.Seek(t.node, metrics.byteCount, offset)
+	if leaf.Count > 0 {
+		before = before.add(leafMetrics(string(leaf.Leaf[:offset-start])))
+	}
+
+	line = before.lines
+	if before.last == '\r' && offset < t.Len() && t.prefix(offset, 1) == "\n" {
+		line--
+		offset = t.LineEnd(line)
+	}
+	return line, offset - t.LineStart(line)
+}
+
+// LineColToOffset converts a line and a column (see OffsetToLineCol)
+// to a byte offset.  It panics if the column is past the end of the
+// line or not on a rune boundary.
+func (t Text) LineColToOffset(line, col int) int {
+	start := t.LineStart(line)
+	if col < 0 || start+col > t.LineEnd(line) {
+		panic("Unexpected column")
+	}
+	t.checkRange(start+col, 0)
+	return start + col
+}
+
+func (t Text) checkLine(line int) {
+	if line < 0 || line >= t.LineCount() {
+		panic("Unexpected line")
+	}
+}
+
+func (m metrics) lineCount() int {
+	return m.lines
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package text_test
+
+import (
+	"github.com/perdata/trope/text"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var lineBreak = regexp.MustCompile("\r\n|\n|\r")
+
+func TestLines(t *testing.T) {
+	rand.Seed(42)
+	x := text.New(randomRunes(3000))
+	for kk := 0; kk < 300; kk++ {
+		offset := rand.Intn(x.RuneCount() + 1)
+		count := rand.Intn(x.RuneCount()-offset+1) / 50
+		x = x.SpliceRunes(offset, count, text.New(randomRunes(rand.Intn(10))))
+
+		if kk%30 == 0 {
+			checkLines(t, x)
+		}
+	}
+	checkLines(t, x)
+}
+
+func checkLines(t *testing.T, x text.Text) {
+	t.Helper()
+	str := x.String()
+	lines := lineBreak.Split(str, -1)
+	starts := []int{0}
+	for _, loc := range lineBreak.FindAllStringIndex(str, -1) {
+		starts = append(starts, loc[1])
+	}
+
+	if x.LineCount() != len(lines) {
+		t.Fatal("Unexpected line count", x.LineCount(), len(lines))
+	}
+	for kk, line := range lines {
+		if x.LineStart(kk) != starts[kk] || x.Line(kk).String() != line {
+			t.Fatal("Unexpected line", kk, x.LineStart(kk), starts[kk])
+		}
+		if x.LineEnd(kk) != starts[kk]+len(line) {
+			t.Fatal("Unexpected line end", kk)
+		}
+	}
+
+	count := 0
+	for kk, line := range x.Lines() {
+		if kk != count || line.String() != lines[kk] {
+			t.Fatal("Unexpected line", kk)
+		}
+		count++
+	}
+	if count != len(lines) {
+		t.Fatal("Unexpected number of lines", count)
+	}
+
+	for kk := 0; kk < 100; kk++ {
+		offset := x.ByteOffset(rand.Intn(x.RuneCount() + 1))
+		line, col := x.OffsetToLineCol(offset)
+		expected := strings.Count(lineBreak.ReplaceAllString(str[:offset], "\n"), "\n")
+		if offset > 0 && offset < len(str) && str[offset-1] == '\r' && str[offset] == '\n' {
+			expected--
+			offset--
+		}
+		if line != expected || col != offset-starts[line] || x.LineColToOffset(line, col) != offset {
+			t.Fatal("Unexpected line, col", offset, line, col, expected)
+		}
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	x := text.New("a\r\nb\rc\n\nd\r")
+	if x.LineCount() != 6 {
+		t.Fatal("Unexpected line count", x.LineCount())
+	}
+	expected := []string{"a", "b", "c", "", "d", ""}
+	for kk, line := range x.Lines() {
+		if line.String() != expected[kk] {
+			t.Fatal("Unexpected line", kk, line.String())
+		}
+	}
+
+	// a "\r\n" split between two leaves
+	a := strings.Repeat("a", 1023)
+	y := text.New(a + "\r\nb")
+	if leaf, _ := y.Node().At(1023); leaf[len(leaf)-1] != '\r' {
+		t.Fatal("Unexpected leaves", len(leaf))
+	}
+	if y.LineCount() != 2 || y.LineStart(1) != 1025 || y.Line(0).String() != a {
+		t.Fatal("Unexpected lines", y.LineCount(), y.LineStart(1))
+	}
+	if line, col := y.OffsetToLineCol(1024); line != 0 || col != 1023 {
+		t.Fatal("Unexpected line, col", line, col)
+	}
+
+	if line, col := text.New("héllo\nwörld").OffsetToLineCol(10); line != 1 || col != 3 {
+		t.Fatal("Unexpected line, col", line, col)
+	}
+	mustPanic(t, func() { x.LineStart(6) })
+	mustPanic(t, func() { x.LineColToOffset(0, 2) })
+	mustPanic(t, func() { text.New("é").LineColToOffset(0, 1) })
+	if (text.Text{}).LineCount() != 1 {
+		t.Fatal("Unexpected empty line count")
+	}
+}
+
+func TestLineMetricsStored(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	var docs []text.Text
+	for kk := 0; kk < 10; kk++ {
+		docs = append(docs, text.New(strings.Repeat("the quick brown fox jumps over\n", 5000)))
+	}
+
+	// once computed, the metrics of every node of every text are
+	// kept, so lookups do not summarize any leaf again
+	for _, doc := range docs {
+		doc.LineStart(doc.LineCount() - 1)
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, doc := range docs {
+			// the last line is the empty one after the final newline
+			line := r.Intn(doc.LineCount() - 1)
+			if doc.LineStart(line) != 31*line {
+				t.Fatal("Unexpected line start", line)
+			}
+			if l, col := doc.OffsetToLineCol(31*line + 5); l != line || col != 5 {
+				t.Fatal("Unexpected line, col", l, col)
+			}
+		}
+	})
+	if allocs > 0 {
+		t.Fatal("Unexpected allocations", allocs)
+	}
+}
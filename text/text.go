@@ -0,0 +1,251 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+// Package text implements a UTF-8 aware text rope on top of
+// generic.Node.
+//
+// The elements of the underlying node are bytes, so Len and the
+// offsets of Slice and Splice are in bytes.  Those offsets must fall
+// on rune boundaries.  The Runes variants take offsets in runes
+// instead.  The number of runes and lines of every node is tracked
+// so converting between the two is logarithmic.
+package text
+
+import (
+	"github.com/perdata/trope/generic"
+	"strings"
+	"unicode/utf8"
+)
+
+// options keeps the tree balanced with leaves of roughly a kilobyte
+var options = generic.Options{Balanced: true, MaxFanout: 32, LeafSize: 1024, MinLeafSize: 256}
+
+// Text is an immutable UTF-8 string.  The zero value is an empty
+// text.
+type Text struct {
+	node generic.Node[generic.String]
+}
+
+// New creates a text from s.  Invalid UTF-8 sequences are replaced
+// by utf8.RuneError.
+func New(s string) Text {
+	s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	n := generic.NewWithOptions(generic.String(""), 0, options)
+	for s != "" {
+		size := min(len(s), options.LeafSize)
+		for size < len(s) && !utf8.RuneStart(s[size]) {
+			size--
+		}
+		n = n.Append(generic.String(s[:size]), size)
+		s = s[size:]
+	}
+	return Text{n}
+}
+
+// Node returns the underlying node.  Its leaves are not split on
+// rune boundaries.
+func (t Text) Node() generic.Node[generic.String] {
+	return t.node
+}
+
+// Len returns the number of bytes in the text
+func (t Text) Len() int {
+	return t.node.Count
+}
+
+// RuneCount returns the number of runes in the text
+func (t Text) RuneCount() int {
+	return summary.Of(t.node).runes
+}
+
+// String returns the contents of the text
+func (t Text) String() string {
+	var b strings.Builder
+	b.Grow(t.Len())
+	t.node.ForEach(func(v generic.String, count int) {
+		b.WriteString(string(v))
+	})
+	return b.String()
+}
+
+// Bytes returns the contents of the text as a new byte slice
+func (t Text) Bytes() []byte {
+	b := make([]byte, 0, t.Len())
+	t.node.ForEach(func(v generic.String, count int) {
+		b = append(b, v...)
+	})
+	return b
+}
+
+// Slice returns the text between the byte offsets offset and
+// offset+count.  It panics if either is not a rune boundary.
+func (t Text) Slice(offset, count int) Text {
+	t.checkRange(offset, count)
+	t.node = t.node.Slice(offset, count)
+	return t
+}
+
+// Splice replaces the text between the byte offsets offset and
+// offset+count with the replacement.  It panics if either is not a
+// rune boundary.
+func (t Text) Splice(offset, count int, replacement Text) Text {
+	t.checkRange(offset, count)
+	if t.node.Forest().IDSource() == nil {
+		t = New("")
+	}
+	t.node = t.node.Splice(offset, count, replacement.node)
+	return t
+}
+
+// RuneAt returns the rune at the rune offset index
+func (t Text) RuneAt(index int) rune {
+	if index < 0 || index >= t.RuneCount() {
+		panic("Unexpected index")
+	}
+	r, _ := utf8.DecodeRuneInString(t.prefix(t.ByteOffset(index), utf8.UTFMax))
+	return r
+}
+
+// SliceRunes is like Slice but with rune offsets
+func (t Text) SliceRunes(offset, count int) Text {
+	start, end := t.runeRange(offset, count)
+	return t.Slice(start, end-start)
+}
+
+// SpliceRunes is like Splice but with rune offsets
+func (t Text) SpliceRunes(offset, count int, replacement Text) Text {
+	start, end := t.runeRange(offset, count)
+	return t.Splice(start, end-start, replacement)
+}
+
+// ByteOffset converts a rune offset to a byte offset.  A rune offset
+// equal to RuneCount maps to Len.
+func (t Text) ByteOffset(runes int) int {
+	if runes < 0 || runes > t.RuneCount() {
+		panic("Unexpected offset")
+	}
+	if runes == t.RuneCount() {
+		return t.Len()
+	}
+
+	leaf, offset, before := summary.Seek(t.node, metrics.runeCount, runes)
+	runes -= before.runes
+	for kk := 0; ; kk++ {
+		if utf8.RuneStart(leaf.Leaf[kk]) {
+			if runes == 0 {
+				return offset + kk
+			}
+			runes--
+		}
+	}
+}
+
+// RuneOffset converts a byte offset to a rune offset.  It panics if
+// the offset is not a rune boundary.
+func (t Text) RuneOffset(offset int) int {
+	t.checkRange(offset, 0)
+	leaf, start, before := summary.Seek(t.node, metrics.byteCount, offset)
+	if leaf.Count == 0 {
+		return before.runes
+	}
+	return before.runes + countRunes(string(leaf.Leaf[:offset-start]))
+}
+
+func (t Text) runeRange(offset, count int) (int, int) {
+	if offset < 0 || count < 0 || offset+count > t.RuneCount() {
+		panic("Unexpected offset, count")
+	}
+	return t.ByteOffset(offset), t.ByteOffset(offset + count)
+}
+
+func (t Text) checkRange(offset, count int) {
+	if offset < 0 || count < 0 || offset+count > t.Len() {
+		panic("Unexpected offset, count")
+	}
+	if !t.isBoundary(offset) || !t.isBoundary(offset+count) {
+		panic("Unexpected offset, count")
+	}
+}
+
+func (t Text) isBoundary(offset int) bool {
+	if offset == t.Len() {
+		return true
+	}
+	leaf, kk := t.node.At(offset)
+	return utf8.RuneStart(leaf[kk])
+}
+
+// prefix returns up to count bytes starting at the byte offset
+func (t Text) prefix(offset, count int) string {
+	var b strings.Builder
+	for leaf, size := range t.node.LeavesFrom(offset) {
+		if b.Len() >= count {
+			break
+		}
+		b.WriteString(string(leaf[:min(size, count-b.Len())]))
+	}
+	return b.String()
+}
+
+// summary tracks the metrics of the nodes of all texts.  The metrics
+// are stored on each node once computed, so the texts do not compete
+// for them.
+var summary = generic.NewSummary(func(v generic.String, count int) metrics {
+	return leafMetrics(string(v))
+}, metrics.add)
+
+// metrics are the counts tracked for every node.  Lines counts line
+// breaks ("\n", "\r\n" or a lone "\r").  The counts add up even when
+// a leaf boundary splits a rune or a "\r\n".
+type metrics struct {
+	bytes, runes, lines int
+	first, last         byte
+}
+
+// add returns the metrics of the concatenation
+func (m metrics) add(o metrics) metrics {
+	switch {
+	case m.bytes == 0:
+		return o
+	case o.bytes == 0:
+		return m
+	}
+	result := metrics{m.bytes + o.bytes, m.runes + o.runes, m.lines + o.lines, m.first, o.last}
+	if m.last == '\r' && o.first == '\n' {
+		result.lines--
+	}
+	return result
+}
+
+func leafMetrics(s string) metrics {
+	if s == "" {
+		return metrics{}
+	}
+	m := metrics{len(s), countRunes(s), 0, s[0], s[len(s)-1]}
+	for kk := 0; kk < len(s); kk++ {
+		if s[kk] == '\n' || s[kk] == '\r' && (kk+1 == len(s) || s[kk+1] != '\n') {
+			m.lines++
+		}
+	}
+	return m
+}
+
+// countRunes counts rune starts so that split runes are counted once
+func countRunes(s string) int {
+	count := 0
+	for kk := 0; kk < len(s); kk++ {
+		if utf8.RuneStart(s[kk]) {
+			count++
+		}
+	}
+	return count
+}
+
+func (m metrics) runeCount() int {
+	return m.runes
+}
+
+func (m metrics) byteCount() int {
+	return m.bytes
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2018 Ramesh Vyaghrapuri. All rights reserved.
+// Use of this source code is governed by a MIT-style license
+// that can be found in the LICENSE file.
+
+package text_test
+
+import (
+	"github.com/perdata/trope/text"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var alphabet = []rune("abc é€😀\n\r")
+
+func randomRunes(count int) string {
+	result := make([]rune, count)
+	for kk := range result {
+		result[kk] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return string(result)
+}
+
+func mustPanic(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Did not panic")
+		}
+	}()
+	fn()
+}
+
+func TestText(t *testing.T) {
+	rand.Seed(42)
+	expected := []rune(randomRunes(3000))
+	x := text.New(string(expected))
+
+	for kk := 0; kk < 500; kk++ {
+		offset := rand.Intn(len(expected) + 1)
+		count := rand.Intn(len(expected)-offset+1) / 50
+		insert := []rune(randomRunes(rand.Intn(20)))
+		x = x.SpliceRunes(offset, count, text.New(string(insert)))
+		expected = append(expected[:offset:offset], append(insert, expected[offset+count:]...)...)
+
+		if x.RuneCount() != len(expected) || x.Len() != len(string(expected)) {
+			t.Fatal("Unexpected counts", x.RuneCount(), len(expected))
+		}
+		if kk%50 == 0 && x.String() != string(expected) {
+			t.Fatal("Diverged")
+		}
+	}
+
+	str := string(expected)
+	if x.String() != str || string(x.Bytes()) != str {
+		t.Fatal("Diverged")
+	}
+	for kk := 0; kk < 200; kk++ {
+		index := rand.Intn(len(expected))
+		if x.RuneAt(index) != expected[index] {
+			t.Fatal("Unexpected rune", index)
+		}
+		offset := x.ByteOffset(index)
+		if offset != len(string(expected[:index])) || x.RuneOffset(offset) != index {
+			t.Fatal("Unexpected offset", index, offset)
+		}
+		count := rand.Intn(len(expected) - index + 1)
+		if x.SliceRunes(index, count).String() != string(expected[index:index+count]) {
+			t.Fatal("Unexpected slice", index, count)
+		}
+	}
+	if x.ByteOffset(len(expected)) != x.Len() || x.RuneOffset(x.Len()) != len(expected) {
+		t.Fatal("Unexpected end offsets")
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	x := text.New("héllo wörld")
+	if x.Len() != 13 || x.RuneCount() != 11 || x.RuneAt(1) != 'é' {
+		t.Fatal("Unexpected text", x.Len(), x.RuneCount())
+	}
+	mustPanic(t, func() { x.Slice(2, 1) })
+	mustPanic(t, func() { x.Splice(0, 2, text.New("")) })
+	mustPanic(t, func() { x.RuneOffset(9) })
+	mustPanic(t, func() { x.RuneAt(11) })
+	mustPanic(t, func() { x.SliceRunes(5, 7) })
+
+	if s := x.Splice(1, 2, text.New("e")).String(); s != "hello wörld" {
+		t.Fatal("Unexpected splice", s)
+	}
+	if s := x.Slice(8, 2).String(); s != "ö" {
+		t.Fatal("Unexpected slice", s)
+	}
+	if s := text.New("a\xffb").String(); s != "a�b" {
+		t.Fatal("Unexpected invalid UTF-8 handling", s)
+	}
+}
+
+func TestLargeText(t *testing.T) {
+	str := strings.Repeat("€", 2000)
+	x := text.New(str)
+	if x.Len() != len(str) || x.RuneCount() != 2000 || x.String() != str {
+		t.Fatal("Unexpected text")
+	}
+	for kk := 0; kk < 2000; kk += 333 {
+		if x.RuneAt(kk) != '€' || x.ByteOffset(kk) != 3*kk {
+			t.Fatal("Unexpected rune", kk)
+		}
+	}
+
+	var zero text.Text
+	if zero.String() != "" || zero.RuneCount() != 0 || !utf8.ValidString(zero.Splice(0, 0, x).String()) {
+		t.Fatal("Unexpected zero text")
+	}
+}
+
+func TestRuneMetricsStored(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	var docs []text.Text
+	for kk := 0; kk < 10; kk++ {
+		docs = append(docs, text.New(strings.Repeat("€uro", 20000)))
+	}
+
+	// once computed, the metrics of every node of every text are
+	// kept, so conversions do not summarize any leaf again
+	for _, doc := range docs {
+		doc.RuneCount()
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, doc := range docs {
+			runes := r.Intn(doc.RuneCount()/4) * 4
+			if doc.ByteOffset(runes) != 6*runes/4 || doc.RuneOffset(6*runes/4) != runes {
+				t.Fatal("Unexpected offset", runes)
+			}
+		}
+	})
+	if allocs > 0 {
+		t.Fatal("Unexpected allocations", allocs)
+	}
+}